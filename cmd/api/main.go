@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"skyhow/internal/auth"
@@ -30,25 +32,75 @@ func main() {
 
 	userStore := store.NewUserStore(db)
 
-	discordOAuth, err := auth.NewDiscordOAuth(
-		os.Getenv("DISCORD_CLIENT_ID"),
-		os.Getenv("DISCORD_CLIENT_SECRET"),
-		os.Getenv("DISCORD_REDIRECT_URL"),
-	)
-	if err != nil {
-		log.Fatal(err)
+	var providers []auth.Provider
+
+	if os.Getenv("DISCORD_CLIENT_ID") != "" {
+		discordOAuth, err := auth.NewDiscordOAuth(
+			os.Getenv("DISCORD_CLIENT_ID"),
+			os.Getenv("DISCORD_CLIENT_SECRET"),
+			os.Getenv("DISCORD_REDIRECT_URL"),
+			os.Getenv("DISCORD_BASE_URL"),
+			nil,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if guildID := os.Getenv("DISCORD_GUILD_ID"); guildID != "" {
+			roles, err := auth.ParseDiscordRoleMap(os.Getenv("DISCORD_ROLE_MAP"))
+			if err != nil {
+				log.Fatal(err)
+			}
+			discordOAuth.SetGuild(auth.DiscordGuild{
+				ID:            guildID,
+				Roles:         roles,
+				RequireMember: os.Getenv("DISCORD_REQUIRE_GUILD_MEMBER") == "true",
+			})
+		}
+		providers = append(providers, discordOAuth)
+	}
+
+	if os.Getenv("OIDC_ISSUER_URL") != "" {
+		name := os.Getenv("OIDC_PROVIDER_NAME")
+		if name == "" {
+			name = "oidc"
+		}
+		oidc, err := auth.NewOIDCProvider(
+			context.Background(),
+			name,
+			os.Getenv("OIDC_ISSUER_URL"),
+			os.Getenv("OIDC_CLIENT_ID"),
+			os.Getenv("OIDC_CLIENT_SECRET"),
+			os.Getenv("OIDC_REDIRECT_URL"),
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+		providers = append(providers, oidc)
+	}
+
+	if len(providers) == 0 {
+		log.Fatal("no oauth providers configured")
 	}
+
 	sessionStore := store.NewSessionStore(db)
+	auditService := services.NewAuditService(store.NewAuditStore(db))
 	authSvc := services.NewAuthService(
-		discordOAuth,
+		providers,
 		userStore,
 		sessionStore,
-		14*24*time.Hour,
+		store.NewOAuthStateStore(db),
+		auditService,
+		durationEnv("SESSION_TTL", 14*24*time.Hour),
+		durationEnv("SESSION_MAX_LIFETIME", 90*24*time.Hour),
 	)
 
-	discordHandler := handlers.NewDiscordAuthHandler(
-		discordOAuth,
-		userStore,
+	go authSvc.RunExpiredSessionSweeper(
+		context.Background(),
+		durationEnv("SESSION_SWEEP_INTERVAL", time.Hour),
+		intEnv("SESSION_SWEEP_BATCH_SIZE", 1000),
+	)
+
+	oauthHandler := handlers.NewOAuthHandler(
 		sessionStore,
 		authSvc,
 		os.Getenv("COOKIE_SECURE") == "true",
@@ -56,18 +108,67 @@ func main() {
 	)
 
 	guideStore := store.NewGuideStore(db)
-	guideService := services.NewGuideService(guideStore)
+	guideService := services.NewGuideService(guideStore, auditService)
 	guideHandler := handlers.NewGuideHandler(guideService)
 
-	router := httpapi.NewRouter(httpapi.RouterDeps{
-		DiscordAuth:  discordHandler,
-		Guides:       guideHandler,
-		Users:        userStore,
-		Sessions:     sessionStore,
-		CookieSecure: os.Getenv("COOKIE_SECURE") == "true",
-		CookieDomain: os.Getenv("COOKIE_DOMAIN"),
+	tagStore := store.NewTagStore(db)
+	tagService := services.NewTagService(tagStore, auditService)
+	tagHandler := handlers.NewTagHandler(tagService)
+
+	go guideService.RunScheduler(
+		context.Background(),
+		durationEnv("GUIDE_SCHEDULER_INTERVAL", time.Minute),
+		intEnv("GUIDE_SCHEDULER_BATCH_SIZE", 100),
+	)
+
+	go tagService.RunOrphanCleanup(context.Background(), durationEnv("TAG_CLEANUP_INTERVAL", time.Hour))
+
+	router, err := httpapi.NewRouter(httpapi.RouterDeps{
+		OAuth:          oauthHandler,
+		Guides:         guideHandler,
+		Tags:           tagHandler,
+		UserSessions:   handlers.NewSessionHandler(authSvc),
+		Identities:     handlers.NewIdentityHandler(authSvc),
+		AdminUsers:     handlers.NewUserAdminHandler(services.NewUserService(userStore, guideStore, auditService)),
+		Audit:          handlers.NewAuditHandler(auditService),
+		Users:          userStore,
+		Sessions:       sessionStore,
+		SessionTTL:     authSvc.SessionTTL,
+		CookieSecure:   os.Getenv("COOKIE_SECURE") == "true",
+		CookieDomain:   os.Getenv("COOKIE_DOMAIN"),
+		TrustedOrigins: strings.Split(os.Getenv("CSRF_TRUSTED_ORIGINS"), ","),
+		TrustedProxies: strings.Split(os.Getenv("TRUSTED_PROXIES"), ","),
 	})
+	if err != nil {
+		log.Fatal("invalid TRUSTED_PROXIES: ", err)
+	}
 
 	log.Println("listening on :8080")
 	log.Fatal(router.Run(":8080"))
 }
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid %s %q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+func intEnv(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid %s %q, using %d", key, v, def)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,61 @@
+// Command fakediscord serves the discordtest fake so the API can be run
+// without network access. Point the API at it with
+// DISCORD_BASE_URL=http://localhost:9090; it reads the same DISCORD_CLIENT_ID
+// and DISCORD_CLIENT_SECRET.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"skyhow/internal/auth/discordtest"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	_ = godotenv.Load()
+
+	addr := os.Getenv("FAKE_DISCORD_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+
+	user := discordtest.User{
+		ID:         envOr("FAKE_DISCORD_USER_ID", "100000000000000001"),
+		Username:   envOr("FAKE_DISCORD_USERNAME", "fakeuser"),
+		GlobalName: envOr("FAKE_DISCORD_GLOBAL_NAME", "Fake User"),
+		Email:      envOr("FAKE_DISCORD_EMAIL", "fakeuser@example.com"),
+		Verified:   true,
+	}
+
+	// The fake user is a member of DISCORD_GUILD_ID with the role ids in
+	// FAKE_DISCORD_ROLE_IDS, so role mapping can be tried offline.
+	if guildID := os.Getenv("DISCORD_GUILD_ID"); guildID != "" {
+		var roles []string
+		for _, id := range strings.Split(os.Getenv("FAKE_DISCORD_ROLE_IDS"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				roles = append(roles, id)
+			}
+		}
+		user.GuildRoles = map[string][]string{guildID: roles}
+	}
+
+	fake := discordtest.NewFake(
+		os.Getenv("DISCORD_CLIENT_ID"),
+		os.Getenv("DISCORD_CLIENT_SECRET"),
+		user,
+	)
+
+	log.Println("fake discord listening on", addr)
+	log.Fatal(http.ListenAndServe(addr, fake.Handler()))
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -9,19 +9,57 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
 )
 
+// DefaultDiscordBaseURL is where the Discord API lives in production.
+const DefaultDiscordBaseURL = "https://discord.com"
+
 type DiscordOAuth struct {
-	oauth *oauth2.Config
+	oauth   *oauth2.Config
+	baseURL string
+	client  *http.Client
+	guild   *DiscordGuild
+}
+
+// DiscordGuild maps roles in our Discord server to skyhow roles.
+type DiscordGuild struct {
+	ID string
+	// Roles maps Discord role ids to skyhow roles. A member with several
+	// mapped roles gets the highest one; a member with none keeps whatever
+	// role they have in skyhow.
+	Roles map[string]string
+	// RequireMember refuses logins from accounts that aren't in the guild.
+	RequireMember bool
+}
+
+var ErrNotGuildMember = errors.New("discord account is not a member of the guild")
+
+// guildRoleRank orders the roles a guild role can grant.
+var guildRoleRank = map[string]int{
+	"user":        0,
+	"contributor": 1,
+	"editor":      2,
+	"admin":       3,
 }
 
-func NewDiscordOAuth(clientID, clientSecret, redirectURL string) (*DiscordOAuth, error) {
+// NewDiscordOAuth configures Discord login. baseURL defaults to
+// DefaultDiscordBaseURL and client to one with a 10 second timeout; both can be
+// swapped out to talk to a fake Discord such as the discordtest package.
+func NewDiscordOAuth(clientID, clientSecret, redirectURL, baseURL string, client *http.Client) (*DiscordOAuth, error) {
 	if clientID == "" || clientSecret == "" || redirectURL == "" {
 		return nil, errors.New("discord oauth config missing env vars")
 	}
+	if baseURL == "" {
+		baseURL = DefaultDiscordBaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
 
 	cfg := &oauth2.Config{
 		ClientID:     clientID,
@@ -29,19 +67,51 @@ func NewDiscordOAuth(clientID, clientSecret, redirectURL string) (*DiscordOAuth,
 		RedirectURL:  redirectURL,
 		Scopes:       []string{"identify", "email"},
 		Endpoint: oauth2.Endpoint{
-			AuthURL:  "https://discord.com/api/oauth2/authorize",
-			TokenURL: "https://discord.com/api/oauth2/token",
+			AuthURL:  baseURL + "/api/oauth2/authorize",
+			TokenURL: baseURL + "/api/oauth2/token",
 		},
 	}
-	return &DiscordOAuth{oauth: cfg}, nil
+	return &DiscordOAuth{oauth: cfg, baseURL: baseURL, client: client}, nil
+}
+
+// SetGuild turns on guild role sync. Logins then also request the
+// guilds.members.read scope and Profile.Role is set from the member's roles.
+func (d *DiscordOAuth) SetGuild(g DiscordGuild) {
+	d.guild = &g
+	d.oauth.Scopes = []string{"identify", "email", "guilds.members.read"}
 }
 
-func (d *DiscordOAuth) AuthURL(state string) string {
-	return d.oauth.AuthCodeURL(state, oauth2.AccessTypeOnline)
+// ParseDiscordRoleMap parses "roleID:role,roleID:role" into a role map.
+func ParseDiscordRoleMap(v string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, role, ok := strings.Cut(pair, ":")
+		id, role = strings.TrimSpace(id), strings.TrimSpace(role)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid discord role mapping %q", pair)
+		}
+		if rank, known := guildRoleRank[role]; !known || rank == 0 {
+			return nil, fmt.Errorf("invalid skyhow role %q in discord role mapping", role)
+		}
+		out[id] = role
+	}
+	return out, nil
 }
 
-func (d *DiscordOAuth) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
-	return d.oauth.Exchange(ctx, code)
+func (d *DiscordOAuth) Name() string {
+	return "discord"
+}
+
+func (d *DiscordOAuth) AuthURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return d.oauth.AuthCodeURL(state, append([]oauth2.AuthCodeOption{oauth2.AccessTypeOnline}, opts...)...)
+}
+
+func (d *DiscordOAuth) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return d.oauth.Exchange(context.WithValue(ctx, oauth2.HTTPClient, d.client), code, opts...)
 }
 
 type DiscordMe struct {
@@ -55,14 +125,13 @@ type DiscordMe struct {
 }
 
 func (d *DiscordOAuth) FetchMe(ctx context.Context, token *oauth2.Token) (DiscordMe, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://discord.com/api/users/@me", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+"/api/users/@me", nil)
 	if err != nil {
 		return DiscordMe{}, err
 	}
 	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := d.client.Do(req)
 	if err != nil {
 		return DiscordMe{}, err
 	}
@@ -79,6 +148,89 @@ func (d *DiscordOAuth) FetchMe(ctx context.Context, token *oauth2.Token) (Discor
 	return me, nil
 }
 
+func (d *DiscordOAuth) FetchProfile(ctx context.Context, token *oauth2.Token) (Profile, error) {
+	me, err := d.FetchMe(ctx, token)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	displayName := me.GlobalName
+	if displayName == "" {
+		displayName = me.Username
+	}
+
+	var avatarURL *string
+	if me.Avatar != "" {
+		u := "https://cdn.discordapp.com/avatars/" + me.ID + "/" + me.Avatar + ".png?size=128"
+		avatarURL = &u
+	}
+
+	var role string
+	if d.guild != nil {
+		roles, member, err := d.fetchGuildRoles(ctx, token)
+		if err != nil {
+			return Profile{}, err
+		}
+		if !member && d.guild.RequireMember {
+			return Profile{}, ErrNotGuildMember
+		}
+		role = d.mapGuildRoles(roles)
+	}
+
+	return Profile{
+		ProviderUserID: me.ID,
+		Email:          me.Email,
+		EmailVerified:  me.Verified,
+		DisplayName:    displayName,
+		AvatarURL:      avatarURL,
+		Role:           role,
+	}, nil
+}
+
+// fetchGuildRoles returns the user's role ids in the configured guild.
+// member is false when the user isn't in the guild.
+func (d *DiscordOAuth) fetchGuildRoles(ctx context.Context, token *oauth2.Token) ([]string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+"/api/users/@me/guilds/"+url.PathEscape(d.guild.ID)+"/member", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, false, fmt.Errorf("discord guild member lookup failed: %s", resp.Status)
+	}
+
+	var member struct {
+		Roles []string `json:"roles"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&member); err != nil {
+		return nil, false, err
+	}
+	return member.Roles, true, nil
+}
+
+// mapGuildRoles returns the highest skyhow role the guild roles grant, or ""
+// when none of them is mapped.
+func (d *DiscordOAuth) mapGuildRoles(roleIDs []string) string {
+	role := ""
+	for _, id := range roleIDs {
+		mapped, ok := d.guild.Roles[id]
+		if ok && (role == "" || guildRoleRank[mapped] > guildRoleRank[role]) {
+			role = mapped
+		}
+	}
+	return role
+}
+
 func RandomState() (string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"skyhow/internal/auth/discordtest"
+)
+
+func TestParseDiscordRoleMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", in: "", want: map[string]string{}},
+		{name: "single", in: "111:editor", want: map[string]string{"111": "editor"}},
+		{
+			name: "several with spaces and trailing comma",
+			in:   " 111 : contributor , 222:admin,",
+			want: map[string]string{"111": "contributor", "222": "admin"},
+		},
+		{name: "missing colon", in: "111", wantErr: true},
+		{name: "missing id", in: ":editor", wantErr: true},
+		{name: "missing role", in: "111:", wantErr: true},
+		{name: "unknown role", in: "111:owner", wantErr: true},
+		{name: "user role", in: "111:user", wantErr: true},
+		{name: "role names are case sensitive", in: "111:Editor", wantErr: true},
+		{name: "one bad pair fails the whole map", in: "111:editor,222:owner", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDiscordRoleMap(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDiscordRoleMap: %v", err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapGuildRoles(t *testing.T) {
+	d := &DiscordOAuth{guild: &DiscordGuild{
+		ID: "guild",
+		Roles: map[string]string{
+			"c": "contributor",
+			"e": "editor",
+			"a": "admin",
+		},
+	}}
+
+	tests := []struct {
+		name  string
+		roles []string
+		want  string
+	}{
+		{name: "no roles", roles: nil, want: ""},
+		{name: "only unmapped roles", roles: []string{"x", "y"}, want: ""},
+		{name: "one mapped role", roles: []string{"x", "c"}, want: "contributor"},
+		{name: "highest wins", roles: []string{"c", "a", "e"}, want: "admin"},
+		{name: "order doesn't matter", roles: []string{"e", "c"}, want: "editor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.mapGuildRoles(tt.roles); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscordFetchProfileGuild(t *testing.T) {
+	guild := DiscordGuild{
+		ID:    "guild-1",
+		Roles: map[string]string{"role-c": "contributor", "role-e": "editor"},
+	}
+
+	tests := []struct {
+		name          string
+		guildRoles    map[string][]string
+		requireMember bool
+		wantRole      string
+		wantErr       error
+	}{
+		{
+			name:       "highest mapped role",
+			guildRoles: map[string][]string{"guild-1": {"role-c", "role-e", "other"}},
+			wantRole:   "editor",
+		},
+		{
+			name:       "member without mapped roles keeps their role",
+			guildRoles: map[string][]string{"guild-1": {"other"}},
+		},
+		{
+			name:          "member without roles passes the membership check",
+			guildRoles:    map[string][]string{"guild-1": nil},
+			requireMember: true,
+		},
+		{
+			name:       "not a member",
+			guildRoles: map[string][]string{"guild-2": {"role-e"}},
+		},
+		{
+			name:          "not a member when membership is required",
+			guildRoles:    nil,
+			requireMember: true,
+			wantErr:       ErrNotGuildMember,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := discordtest.NewFake("client", "secret", discordtest.User{
+				ID:         "42",
+				Username:   "player",
+				GuildRoles: tt.guildRoles,
+			})
+			srv := fake.Start()
+			t.Cleanup(srv.Close)
+
+			d, err := NewDiscordOAuth("client", "secret", "http://localhost/auth/discord/callback", srv.URL, srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := guild
+			g.RequireMember = tt.requireMember
+			d.SetGuild(g)
+
+			code := authorizeFakeDiscord(t, srv.Client(), d.AuthURL("state"))
+			token, err := d.Exchange(context.Background(), code)
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+
+			p, err := d.FetchProfile(context.Background(), token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchProfile: %v", err)
+			}
+			if p.ProviderUserID != "42" || p.Role != tt.wantRole {
+				t.Fatalf("got %+v, want role %q", p, tt.wantRole)
+			}
+		})
+	}
+}
+
+// authorizeFakeDiscord visits the fake's authorize page and returns the code
+// it redirects back with.
+func authorizeFakeDiscord(t *testing.T, client *http.Client, authURL string) string {
+	t.Helper()
+
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := c.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || loc.Query().Get("code") == "" {
+		t.Fatalf("authorize: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	return loc.Query().Get("code")
+}
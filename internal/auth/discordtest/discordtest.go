@@ -0,0 +1,234 @@
+// Package discordtest is an in-memory fake of the parts of Discord that the
+// login flow talks to: the authorize page, the token endpoint and
+// /users/@me. It approves every authorization request as User, so tests and
+// offline development can run the full OAuth flow without network access.
+package discordtest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// User is the Discord account the fake logs everyone in as.
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Email      string `json:"email"`
+	Verified   bool   `json:"verified"`
+	Avatar     string `json:"avatar"`
+
+	// GuildRoles lists the user's role ids per guild id. Guilds not in the
+	// map answer the member lookup with 404.
+	GuildRoles map[string][]string `json:"-"`
+}
+
+type grant struct {
+	redirectURI   string
+	codeChallenge string
+	expiresAt     time.Time
+}
+
+type Fake struct {
+	ClientID     string
+	ClientSecret string
+
+	mu     sync.Mutex
+	user   User
+	codes  map[string]grant
+	tokens map[string]User
+}
+
+func NewFake(clientID, clientSecret string, user User) *Fake {
+	return &Fake{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		user:         user,
+		codes:        make(map[string]grant),
+		tokens:       make(map[string]User),
+	}
+}
+
+// SetUser changes who the next authorization logs in as. Tokens already
+// issued keep their user.
+func (f *Fake) SetUser(u User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.user = u
+}
+
+// Start serves the fake on a local httptest server. Pass its URL as the
+// Discord base URL.
+func (f *Fake) Start() *httptest.Server {
+	return httptest.NewServer(f.Handler())
+}
+
+func (f *Fake) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/oauth2/authorize", f.authorize)
+	mux.HandleFunc("GET /oauth2/authorize", f.authorize)
+	mux.HandleFunc("POST /api/oauth2/token", f.token)
+	mux.HandleFunc("GET /api/users/@me", f.me)
+	mux.HandleFunc("GET /api/users/@me/guilds/{guild}/member", f.guildMember)
+	return mux
+}
+
+// authorize skips the consent screen and redirects straight back with a code.
+func (f *Fake) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("client_id") != f.ClientID {
+		writeError(w, http.StatusBadRequest, "invalid_client")
+		return
+	}
+	if q.Get("response_type") != "code" {
+		writeError(w, http.StatusBadRequest, "unsupported_response_type")
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		writeError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	if m := q.Get("code_challenge_method"); q.Get("code_challenge") != "" && m != "S256" {
+		writeError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	code := randomString()
+
+	f.mu.Lock()
+	f.codes[code] = grant{
+		redirectURI:   redirectURI.String(),
+		codeChallenge: q.Get("code_challenge"),
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	f.mu.Unlock()
+
+	back := redirectURI.Query()
+	back.Set("code", code)
+	if state := q.Get("state"); state != "" {
+		back.Set("state", state)
+	}
+	redirectURI.RawQuery = back.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (f *Fake) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != f.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(f.ClientSecret)) != 1 {
+		writeError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code := r.PostForm.Get("code")
+	g, ok := f.codes[code]
+	delete(f.codes, code)
+	if !ok || time.Now().After(g.expiresAt) || r.PostForm.Get("redirect_uri") != g.redirectURI {
+		writeError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if g.codeChallenge != "" && s256(r.PostForm.Get("code_verifier")) != g.codeChallenge {
+		writeError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	accessToken := randomString()
+	f.tokens[accessToken] = f.user
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   604800,
+		"scope":        "identify email",
+	})
+}
+
+func (f *Fake) me(w http.ResponseWriter, r *http.Request) {
+	u, ok := f.bearerUser(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "401: Unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(u)
+}
+
+func (f *Fake) guildMember(w http.ResponseWriter, r *http.Request) {
+	u, ok := f.bearerUser(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "401: Unauthorized")
+		return
+	}
+
+	roles, ok := u.GuildRoles[r.PathValue("guild")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Unknown Guild")
+		return
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"user":  u,
+		"roles": roles,
+	})
+}
+
+func (f *Fake) bearerUser(r *http.Request) (User, bool) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return User{}, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.tokens[accessToken]
+	return u, ok
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func s256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
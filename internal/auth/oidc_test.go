@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeOIDC is a minimal OpenID provider: discovery, token and userinfo.
+type fakeOIDC struct {
+	srv *httptest.Server
+
+	// issuer overrides the issuer in the discovery document.
+	issuer string
+	// claims go into the id_token returned by the token endpoint.
+	claims   map[string]any
+	userInfo map[string]any
+}
+
+func newFakeOIDC(t *testing.T) *fakeOIDC {
+	t.Helper()
+
+	f := &fakeOIDC{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := f.issuer
+		if issuer == "" {
+			issuer = f.srv.URL
+		}
+		writeJSON(w, map[string]any{
+			"issuer":                 issuer,
+			"authorization_endpoint": f.srv.URL + "/authorize",
+			"token_endpoint":         f.srv.URL + "/token",
+			"userinfo_endpoint":      f.srv.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     unsignedJWT(f.claims),
+		})
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, f.userInfo)
+	})
+
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeOIDC) provider(t *testing.T) *OIDCProvider {
+	t.Helper()
+	p, err := NewOIDCProvider(context.Background(), "test", f.srv.URL, "client-id", "client-secret", "http://localhost/auth/test/callback")
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+	return p
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func unsignedJWT(claims map[string]any) string {
+	enc := func(v any) string {
+		b, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	return enc(map[string]any{"alg": "none"}) + "." + enc(claims) + ".sig"
+}
+
+func TestNewOIDCProviderIssuerMismatch(t *testing.T) {
+	f := newFakeOIDC(t)
+	f.issuer = "https://evil.example"
+
+	_, err := NewOIDCProvider(context.Background(), "test", f.srv.URL, "client-id", "client-secret", "http://localhost/cb")
+	if err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("got %v, want issuer mismatch", err)
+	}
+}
+
+func TestNewOIDCProviderTrailingSlash(t *testing.T) {
+	f := newFakeOIDC(t)
+	f.issuer = f.srv.URL + "/"
+
+	if _, err := NewOIDCProvider(context.Background(), "test", f.srv.URL, "client-id", "client-secret", "http://localhost/cb"); err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+}
+
+func TestOIDCVerifyNonce(t *testing.T) {
+	f := newFakeOIDC(t)
+	p := f.provider(t)
+
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		nonce   string
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			claims: map[string]any{"iss": f.srv.URL, "sub": "user-123", "aud": "client-id", "nonce": "n-1"},
+			nonce:  "n-1",
+		},
+		{
+			name:   "array audience",
+			claims: map[string]any{"iss": f.srv.URL, "sub": "user-123", "aud": []string{"other", "client-id"}, "nonce": "n-1"},
+			nonce:  "n-1",
+		},
+		{
+			name:    "array audience without client",
+			claims:  map[string]any{"iss": f.srv.URL, "sub": "user-123", "aud": []string{"other", "another"}, "nonce": "n-1"},
+			nonce:   "n-1",
+			wantErr: "audience",
+		},
+		{
+			name:    "wrong audience",
+			claims:  map[string]any{"iss": f.srv.URL, "sub": "user-123", "aud": "someone-else", "nonce": "n-1"},
+			nonce:   "n-1",
+			wantErr: "audience",
+		},
+		{
+			name:    "wrong nonce",
+			claims:  map[string]any{"iss": f.srv.URL, "sub": "user-123", "aud": "client-id", "nonce": "n-2"},
+			nonce:   "n-1",
+			wantErr: "nonce",
+		},
+		{
+			name:    "missing nonce",
+			claims:  map[string]any{"iss": f.srv.URL, "sub": "user-123", "aud": "client-id"},
+			nonce:   "n-1",
+			wantErr: "nonce",
+		},
+		{
+			name:    "empty expected nonce",
+			claims:  map[string]any{"iss": f.srv.URL, "sub": "user-123", "aud": "client-id", "nonce": ""},
+			nonce:   "",
+			wantErr: "nonce",
+		},
+		{
+			name:    "missing sub",
+			claims:  map[string]any{"iss": f.srv.URL, "aud": "client-id", "nonce": "n-1"},
+			nonce:   "n-1",
+			wantErr: "sub",
+		},
+		{
+			name:    "wrong issuer",
+			claims:  map[string]any{"iss": "https://evil.example", "sub": "user-123", "aud": "client-id", "nonce": "n-1"},
+			nonce:   "n-1",
+			wantErr: "issuer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.claims = tt.claims
+			token, err := p.Exchange(context.Background(), "good-code")
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+
+			sub, err := p.VerifyNonce(token, tt.nonce)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyNonce: %v", err)
+				}
+				if sub != "user-123" {
+					t.Fatalf("subject = %q, want user-123", sub)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want error about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyNonceMissingIDToken(t *testing.T) {
+	f := newFakeOIDC(t)
+	p := f.provider(t)
+
+	token := (&oauth2.Token{AccessToken: "access-token"}).WithExtra(map[string]any{})
+	if _, err := p.VerifyNonce(token, "n-1"); err == nil {
+		t.Fatal("expected an error for a token without id_token")
+	}
+}
+
+func TestOIDCFetchProfile(t *testing.T) {
+	f := newFakeOIDC(t)
+	p := f.provider(t)
+	f.claims = map[string]any{"iss": f.srv.URL, "aud": "client-id", "nonce": "n-1"}
+
+	token, err := p.Exchange(context.Background(), "good-code")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	f.userInfo = map[string]any{
+		"sub":                "user-123",
+		"email":              "player@example.com",
+		"email_verified":     true,
+		"preferred_username": "player",
+		"picture":            "https://example.com/p.png",
+	}
+	got, err := p.FetchProfile(context.Background(), token)
+	if err != nil {
+		t.Fatalf("FetchProfile: %v", err)
+	}
+	if got.ProviderUserID != "user-123" || got.Email != "player@example.com" || !got.EmailVerified {
+		t.Fatalf("unexpected profile %+v", got)
+	}
+	if got.DisplayName != "player" {
+		t.Fatalf("display name = %q, want preferred_username fallback", got.DisplayName)
+	}
+	if got.AvatarURL == nil || *got.AvatarURL != "https://example.com/p.png" {
+		t.Fatalf("avatar = %v", got.AvatarURL)
+	}
+
+	f.userInfo = map[string]any{"email": "nosub@example.com"}
+	if _, err := p.FetchProfile(context.Background(), token); err == nil {
+		t.Fatal("expected an error for userinfo without sub")
+	}
+}
+
+func TestOIDCExchangeRejectsBadCode(t *testing.T) {
+	f := newFakeOIDC(t)
+	p := f.provider(t)
+
+	if _, err := p.Exchange(context.Background(), "bad-code"); err == nil {
+		t.Fatal("expected an error for a bad code")
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+)
+
+// Provider is an OAuth login provider. Implementations hide the provider's
+// endpoints and user format behind a normalized Profile.
+type Provider interface {
+	// Name is the short identifier used in routes and stored with identities.
+	Name() string
+	AuthURL(state string, opts ...oauth2.AuthCodeOption) string
+	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error)
+	FetchProfile(ctx context.Context, token *oauth2.Token) (Profile, error)
+}
+
+// NonceVerifier is implemented by providers that return an ID token carrying
+// the nonce sent with the authorization request. VerifyNonce returns the
+// token's subject, which the profile fetched afterwards must match.
+type NonceVerifier interface {
+	VerifyNonce(token *oauth2.Token, nonce string) (subject string, err error)
+}
+
+type Profile struct {
+	ProviderUserID string
+	Email          string
+	EmailVerified  bool
+	DisplayName    string
+	AvatarURL      *string
+	// Role is the skyhow role the provider grants, or empty when the
+	// provider doesn't manage roles.
+	Role string
+}
@@ -0,0 +1,77 @@
+// Package authz maps roles to what they are allowed to do. Services and
+// routes check permissions instead of role names, so the meaning of a role
+// lives in one table.
+package authz
+
+import "slices"
+
+type Permission string
+
+const (
+	// GuidesCreate allows writing guides and editing one's own. Without
+	// GuidesPublish, editing a live guide takes it back through review.
+	GuidesCreate Permission = "guides:create"
+	// GuidesSubmit allows submitting one's own guides for review.
+	GuidesSubmit Permission = "guides:submit"
+	// GuidesPublish allows publishing guides without going through review.
+	GuidesPublish Permission = "guides:publish"
+	// GuidesReview allows working the review queue: approving guides or
+	// requesting changes.
+	GuidesReview Permission = "guides:review"
+	// GuidesEditAny allows editing, publishing, unpublishing and deleting
+	// anyone's guides.
+	GuidesEditAny Permission = "guides:edit_any"
+	// GuidesMarkOutdated allows flagging guides as outdated and clearing the
+	// flag.
+	GuidesMarkOutdated Permission = "guides:mark_outdated"
+	// TagsCurate allows editing tag descriptions, categories and colors.
+	TagsCurate Permission = "tags:curate"
+	// TagsManage allows aliasing and merging tags.
+	TagsManage Permission = "tags:manage"
+	// UsersManage allows changing roles and deactivating users.
+	UsersManage Permission = "users:manage"
+	// AuditRead allows reading the audit log.
+	AuditRead Permission = "audit:read"
+)
+
+var rolePermissions = map[string][]Permission{
+	"user": {
+		GuidesCreate,
+		GuidesSubmit,
+	},
+	"contributor": {
+		GuidesCreate,
+		GuidesSubmit,
+	},
+	"editor": {
+		GuidesCreate,
+		GuidesSubmit,
+		GuidesPublish,
+		GuidesReview,
+		GuidesEditAny,
+		GuidesMarkOutdated,
+		TagsCurate,
+	},
+	"admin": {
+		GuidesCreate,
+		GuidesSubmit,
+		GuidesPublish,
+		GuidesReview,
+		GuidesEditAny,
+		GuidesMarkOutdated,
+		TagsCurate,
+		TagsManage,
+		UsersManage,
+		AuditRead,
+	},
+}
+
+// Can reports whether role grants p. Unknown roles grant nothing.
+func Can(role string, p Permission) bool {
+	return slices.Contains(rolePermissions[role], p)
+}
+
+// Permissions returns everything role grants.
+func Permissions(role string) []Permission {
+	return slices.Clone(rolePermissions[role])
+}
@@ -0,0 +1,178 @@
+package diff
+
+import (
+	"errors"
+	"strings"
+)
+
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// MaxLines caps each side of a diff. Time grows with the product of input
+// size and edit distance, so larger inputs are refused rather than diffed.
+const MaxLines = 5000
+
+var ErrTooLarge = errors.New("diff input too large")
+
+// Lines returns a line-level edit script turning a into b, computed with the
+// linear-space variant of Myers' O(ND) algorithm.
+func Lines(a, b string) ([]Line, error) {
+	al, bl := splitLines(a), splitLines(b)
+	if len(al) > MaxLines || len(bl) > MaxLines {
+		return nil, ErrTooLarge
+	}
+	return diff(al, bl), nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(s, "\n")
+}
+
+type point struct {
+	x, y int
+}
+
+type differ struct {
+	a, b []string
+}
+
+func diff(a, b []string) []Line {
+	d := differ{a: a, b: b}
+	path := d.findPath(0, 0, len(a), len(b))
+
+	var out []Line
+	for i := 1; i < len(path); i++ {
+		x, y := path[i-1].x, path[i-1].y
+		end := path[i]
+
+		for x < end.x && y < end.y && a[x] == b[y] {
+			out = append(out, Line{Op: OpEqual, Text: a[x]})
+			x++
+			y++
+		}
+		switch {
+		case end.x-x < end.y-y:
+			out = append(out, Line{Op: OpInsert, Text: b[y]})
+			y++
+		case end.x-x > end.y-y:
+			out = append(out, Line{Op: OpDelete, Text: a[x]})
+			x++
+		}
+		for x < end.x && y < end.y && a[x] == b[y] {
+			out = append(out, Line{Op: OpEqual, Text: a[x]})
+			x++
+			y++
+		}
+	}
+	return out
+}
+
+// findPath returns the corners of an optimal path through the box
+// [left, right) x [top, bottom). Consecutive points are joined by at most one
+// edit plus diagonal moves.
+func (d *differ) findPath(left, top, right, bottom int) []point {
+	start, finish, ok := d.midpoint(left, top, right, bottom)
+	if !ok {
+		return nil
+	}
+
+	head := d.findPath(left, top, start.x, start.y)
+	tail := d.findPath(finish.x, finish.y, right, bottom)
+	if head == nil {
+		head = []point{start}
+	}
+	if tail == nil {
+		tail = []point{finish}
+	}
+	return append(head, tail...)
+}
+
+// midpoint finds the middle snake of the box by searching forwards from the
+// top left and backwards from the bottom right until the two meet. It only
+// keeps the current frontier of each search, so memory is linear in the box
+// size.
+func (d *differ) midpoint(left, top, right, bottom int) (point, point, bool) {
+	width, height := right-left, bottom-top
+	size := width + height
+	if size == 0 {
+		return point{}, point{}, false
+	}
+	delta := width - height
+	odd := delta%2 != 0
+
+	maxStep := (size + 1) / 2
+	off := maxStep + 1
+	vf := make([]int, 2*maxStep+3)
+	vb := make([]int, 2*maxStep+3)
+	vf[off+1] = left
+	vb[off+1] = bottom
+
+	for step := 0; step <= maxStep; step++ {
+		// Forward search.
+		for k := step; k >= -step; k -= 2 {
+			c := k - delta
+			var x, px int
+			if k == -step || (k != step && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+				px = x
+			} else {
+				px = vf[off+k-1]
+				x = px + 1
+			}
+			y := top + (x - left) - k
+			py := y
+			if step != 0 && x == px {
+				py = y - 1
+			}
+			for x < right && y < bottom && d.a[x] == d.b[y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			if odd && c >= -(step-1) && c <= step-1 && y >= vb[off+c] {
+				return point{px, py}, point{x, y}, true
+			}
+		}
+
+		// Backward search, on diagonals c = k - delta.
+		for c := step; c >= -step; c -= 2 {
+			k := c + delta
+			var y, py int
+			if c == -step || (c != step && vb[off+c-1] > vb[off+c+1]) {
+				y = vb[off+c+1]
+				py = y
+			} else {
+				py = vb[off+c-1]
+				y = py - 1
+			}
+			x := left + (y - top) + k
+			px := x
+			if step != 0 && y == py {
+				px = x + 1
+			}
+			for x > left && y > top && d.a[x-1] == d.b[y-1] {
+				x--
+				y--
+			}
+			vb[off+c] = y
+			if !odd && k >= -step && k <= step && x <= vf[off+k] {
+				return point{x, y}, point{px, py}, true
+			}
+		}
+	}
+	return point{}, point{}, false
+}
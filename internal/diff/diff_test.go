@@ -0,0 +1,96 @@
+package diff
+
+import (
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLinesProducesMinimalScript(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a := randomLines(rng, rng.Intn(30))
+		b := randomLines(rng, rng.Intn(30))
+
+		lines := diff(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, l := range lines {
+			switch l.Op {
+			case OpEqual:
+				gotA = append(gotA, l.Text)
+				gotB = append(gotB, l.Text)
+			case OpDelete:
+				gotA = append(gotA, l.Text)
+				edits++
+			case OpInsert:
+				gotB = append(gotB, l.Text)
+				edits++
+			}
+		}
+		if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("script doesn't reproduce inputs: a=%q b=%q", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("got %d edits, want %d: a=%q b=%q", edits, want, a, b)
+		}
+	}
+}
+
+func TestLinesRejectsLargeInput(t *testing.T) {
+	big := strings.Repeat("x\n", MaxLines)
+	if _, err := Lines(big, "y"); err != ErrTooLarge {
+		t.Fatalf("got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestLinesMemoryIsLinear(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 4000; i++ {
+		a = append(a, "a"+strconv.Itoa(i))
+		b = append(b, "b"+strconv.Itoa(i))
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	lines, err := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 8000 {
+		t.Fatalf("got %d lines, want 8000", len(lines))
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 64<<20 {
+		t.Fatalf("allocated %d bytes", alloc)
+	}
+}
+
+func randomLines(rng *rand.Rand, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = string(rune('a' + rng.Intn(4)))
+	}
+	return out
+}
+
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
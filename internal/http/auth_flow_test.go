@@ -0,0 +1,204 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"skyhow/internal/auth"
+	"skyhow/internal/auth/discordtest"
+	"skyhow/internal/http/handlers"
+	"skyhow/internal/services"
+	"skyhow/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestDiscordLoginFlow runs Start -> Callback -> /me -> Logout against the
+// fake Discord. It needs a migrated database in DATABASE_URL and is skipped
+// without one.
+func TestDiscordLoginFlow(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	discordID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	fake := discordtest.NewFake("test-client", "test-secret", discordtest.User{
+		ID:         discordID,
+		Username:   "flowtest",
+		GlobalName: "Flow Test",
+		Email:      "flowtest-" + discordID + "@example.com",
+		Verified:   true,
+	})
+	discordSrv := fake.Start()
+	t.Cleanup(discordSrv.Close)
+
+	// The redirect URL has to point at the API server, which needs the router,
+	// which needs the provider; route through a variable to break the cycle.
+	var router http.Handler
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(api.Close)
+
+	discord, err := auth.NewDiscordOAuth("test-client", "test-secret", api.URL+"/auth/discord/callback", discordSrv.URL, discordSrv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userStore := store.NewUserStore(db)
+	sessionStore := store.NewSessionStore(db)
+	auditService := services.NewAuditService(store.NewAuditStore(db))
+	authSvc := services.NewAuthService(
+		[]auth.Provider{discord},
+		userStore,
+		sessionStore,
+		store.NewOAuthStateStore(db),
+		auditService,
+		time.Hour,
+		24*time.Hour,
+	)
+	guideStore := store.NewGuideStore(db)
+
+	router, err = NewRouter(RouterDeps{
+		OAuth:        handlers.NewOAuthHandler(sessionStore, authSvc, false, ""),
+		Guides:       handlers.NewGuideHandler(services.NewGuideService(guideStore, auditService)),
+		Tags:         handlers.NewTagHandler(services.NewTagService(store.NewTagStore(db), auditService)),
+		UserSessions: handlers.NewSessionHandler(authSvc),
+		Identities:   handlers.NewIdentityHandler(authSvc),
+		AdminUsers:   handlers.NewUserAdminHandler(services.NewUserService(userStore, guideStore, auditService)),
+		Audit:        handlers.NewAuditHandler(auditService),
+		Users:        userStore,
+		Sessions:     sessionStore,
+		SessionTTL:   time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// Start redirects to Discord's authorize page.
+	authorizeURL := expectRedirect(t, client, api.URL+"/auth/discord/start?returnTo=/guides/mine")
+	if u, _ := url.Parse(authorizeURL); u.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorize URL %q is missing the PKCE challenge", authorizeURL)
+	}
+
+	// The fake approves and sends the browser back with a code.
+	callbackURL := expectRedirect(t, client, authorizeURL)
+
+	// The callback logs in and returns to where the user started.
+	if got := expectRedirect(t, client, callbackURL); got != "/guides/mine" {
+		t.Fatalf("callback redirected to %q, want /guides/mine", got)
+	}
+
+	me := getMe(t, client, api.URL)
+	if !me.Authenticated {
+		t.Fatal("not authenticated after login")
+	}
+	if me.User.DisplayName != "Flow Test" || me.User.Role != "user" {
+		t.Fatalf("unexpected user %+v", me.User)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Exec(context.Background(), `delete from public.users where id = $1;`, me.User.ID)
+	})
+
+	// A replayed callback is refused: the state cookie and the stored state
+	// are both gone.
+	resp, err := client.Get(callbackURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("replayed callback: got %d, want 400", resp.StatusCode)
+	}
+
+	// Logout needs the CSRF token handed out by /me.
+	req, err := http.NewRequest(http.MethodPost, api.URL+"/auth/logout", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-CSRF-Token", me.CSRFToken)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("logout: got %d, want 200", resp.StatusCode)
+	}
+
+	if getMe(t, client, api.URL).Authenticated {
+		t.Fatal("still authenticated after logout")
+	}
+}
+
+type meResponse struct {
+	Authenticated bool   `json:"authenticated"`
+	CSRFToken     string `json:"csrf_token"`
+	User          struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+		Role        string `json:"role"`
+	} `json:"user"`
+}
+
+func getMe(t *testing.T, client *http.Client, baseURL string) meResponse {
+	t.Helper()
+
+	resp, err := client.Get(baseURL + "/me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("/me: got %d", resp.StatusCode)
+	}
+
+	var me meResponse
+	if err := json.NewDecoder(resp.Body).Decode(&me); err != nil {
+		t.Fatal(err)
+	}
+	return me
+}
+
+func expectRedirect(t *testing.T, client *http.Client, target string) string {
+	t.Helper()
+
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("GET %s: got %d, want 302", target, resp.StatusCode)
+	}
+	return resp.Header.Get("Location")
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"skyhow/internal/services"
+	"skyhow/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	Audit *services.AuditService
+}
+
+func NewAuditHandler(audit *services.AuditService) *AuditHandler {
+	return &AuditHandler{Audit: audit}
+}
+
+type auditEventResponse struct {
+	ID         string         `json:"id"`
+	ActorID    *string        `json:"actor_id"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   *string        `json:"target_id"`
+	Before     map[string]any `json:"before"`
+	After      map[string]any `json:"after"`
+	IP         *string        `json:"ip"`
+	CreatedAt  string         `json:"created_at"`
+}
+
+func (h *AuditHandler) List(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	params := store.ListAuditParams{
+		ActorID:    c.Query("actor"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Limit:      parseIntDefault(c.Query("limit"), 50),
+		Offset:     parseIntDefault(c.Query("offset"), 0),
+	}
+
+	if params.Since, ok = parseTimeParam(c, "since"); !ok {
+		return
+	}
+	if params.Until, ok = parseTimeParam(c, "until"); !ok {
+		return
+	}
+
+	events, err := h.Audit.ListEvents(c.Request.Context(), &currentUser, params)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	out := make([]auditEventResponse, 0, len(events))
+	for _, e := range events {
+		out = append(out, auditEventResponse{
+			ID:         e.ID,
+			ActorID:    e.ActorID,
+			Action:     e.Action,
+			TargetType: e.TargetType,
+			TargetID:   e.TargetID,
+			Before:     e.Before,
+			After:      e.After,
+			IP:         e.IP,
+			CreatedAt:  e.CreatedAt.Format(timeRFC3339()),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  out,
+		"limit":  params.Limit,
+		"offset": params.Offset,
+	})
+}
+
+// parseTimeParam reads an optional RFC 3339 query param. It writes a 400 and
+// returns false when the value is malformed.
+func parseTimeParam(c *gin.Context, name string) (*time.Time, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return nil, false
+	}
+	return &t, true
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type markOutdatedRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *GuideHandler) MarkOutdated(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	var req markOutdatedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	if err := h.Guides.MarkGuideOutdated(c.Request.Context(), &currentUser, guideID, req.Reason); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *GuideHandler) ClearOutdated(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	if err := h.Guides.ClearGuideOutdated(c.Request.Context(), &currentUser, guideID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type reviewRequest struct {
+	Comment string `json:"comment"`
+}
+
+type reviewResponse struct {
+	ID         string  `json:"id"`
+	ReviewerID *string `json:"reviewer_id"`
+	Decision   string  `json:"decision"`
+	Comment    *string `json:"comment"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+func (h *GuideHandler) Submit(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.Guides.SubmitGuide(c.Request.Context(), &currentUser, guideID, expectedVersion)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", guideETag(version))
+	c.JSON(http.StatusOK, gin.H{"ok": true, "version": version})
+}
+
+func (h *GuideHandler) Approve(c *gin.Context) {
+	h.review(c, true)
+}
+
+func (h *GuideHandler) RequestChanges(c *gin.Context) {
+	h.review(c, false)
+}
+
+func (h *GuideHandler) review(c *gin.Context, approve bool) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	// The comment is optional when approving, so an empty body is fine.
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	var version int
+	var err error
+	if approve {
+		version, err = h.Guides.ApproveGuide(c.Request.Context(), &currentUser, guideID, req.Comment, expectedVersion)
+	} else {
+		version, err = h.Guides.RequestChanges(c.Request.Context(), &currentUser, guideID, req.Comment, expectedVersion)
+	}
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", guideETag(version))
+	c.JSON(http.StatusOK, gin.H{"ok": true, "version": version})
+}
+
+func (h *GuideHandler) ListReviews(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	reviews, err := h.Guides.ListGuideReviews(c.Request.Context(), &currentUser, guideID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	out := make([]reviewResponse, 0, len(reviews))
+	for _, r := range reviews {
+		out = append(out, reviewResponse{
+			ID:         r.ID,
+			ReviewerID: r.ReviewerID,
+			Decision:   r.Decision,
+			Comment:    r.Comment,
+			CreatedAt:  r.CreatedAt.Format(timeRFC3339()),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": out})
+}
+
+func (h *GuideHandler) ReviewQueue(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	limit := parseIntDefault(c.Query("limit"), 50)
+	offset := parseIntDefault(c.Query("offset"), 0)
+
+	guides, err := h.Guides.ListReviewQueue(c.Request.Context(), &currentUser, limit, offset)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	items := make([]guideListItemResponse, 0, len(guides))
+	for _, g := range guides {
+		items = append(items, toGuideListItemResponse(g))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  items,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
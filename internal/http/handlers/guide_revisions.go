@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"skyhow/internal/diff"
+	"skyhow/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type revisionResponse struct {
+	Revision  int      `json:"revision"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content,omitempty"`
+	Tags      []string `json:"tags"`
+	EditorID  *string  `json:"editor_id"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type diffLineDTO struct {
+	Op   diff.Op `json:"op"`
+	Text string  `json:"text"`
+}
+
+func (h *GuideHandler) ListRevisions(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	revs, err := h.Guides.ListGuideRevisions(c.Request.Context(), &currentUser, guideID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	out := make([]revisionResponse, 0, len(revs))
+	for _, r := range revs {
+		out = append(out, toRevisionResponse(r))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": out})
+}
+
+func (h *GuideHandler) GetRevision(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	rev, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+
+	r, err := h.Guides.GetGuideRevision(c.Request.Context(), &currentUser, guideID, rev)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toRevisionResponse(r))
+}
+
+func (h *GuideHandler) DiffRevision(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	rev, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+	against := parseIntDefault(c.Query("against"), rev-1)
+
+	d, err := h.Guides.DiffGuideRevisions(c.Request.Context(), &currentUser, guideID, against, rev)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	lines := make([]diffLineDTO, 0, len(d.Lines))
+	for _, l := range d.Lines {
+		lines = append(lines, diffLineDTO{Op: l.Op, Text: l.Text})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":       against,
+		"to":         rev,
+		"from_title": d.From.Title,
+		"to_title":   d.To.Title,
+		"lines":      lines,
+	})
+}
+
+func (h *GuideHandler) RestoreRevision(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	rev, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.Guides.RestoreGuideRevision(c.Request.Context(), &currentUser, guideID, rev, expectedVersion)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", guideETag(version))
+	c.JSON(http.StatusOK, gin.H{"ok": true, "version": version})
+}
+
+func parseRevisionParam(c *gin.Context) (int, bool) {
+	rev, err := strconv.Atoi(strings.TrimSpace(c.Param("rev")))
+	if err != nil || rev <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return 0, false
+	}
+	return rev, true
+}
+
+func toRevisionResponse(r store.GuideRevision) revisionResponse {
+	tags := r.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return revisionResponse{
+		Revision:  r.Revision,
+		Title:     r.Title,
+		Content:   r.Content,
+		Tags:      tags,
+		EditorID:  r.EditorID,
+		CreatedAt: r.CreatedAt.Format(timeRFC3339()),
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -21,26 +23,73 @@ func NewGuideHandler(guides *services.GuideService) *GuideHandler {
 }
 
 type createGuideRequest struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	Tags    []string `json:"tags"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Tags        []string   `json:"tags"`
+	GameVersion string     `json:"game_version"`
+	PublishAt   *time.Time `json:"publish_at"`
+	ArchiveAt   *time.Time `json:"archive_at"`
 }
 
 type updateGuideRequest struct {
-	Title   string    `json:"title"`
-	Content string    `json:"content"`
-	Tags    *[]string `json:"tags"`
+	Title       string       `json:"title"`
+	Content     string       `json:"content"`
+	Tags        *[]string    `json:"tags"`
+	GameVersion *string      `json:"game_version"`
+	PublishAt   optionalTime `json:"publish_at"`
+	ArchiveAt   optionalTime `json:"archive_at"`
+}
+
+// optionalTime tells a missing field (leave alone) apart from null (clear).
+type optionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *optionalTime) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	if string(b) == "null" {
+		o.Value = nil
+		return nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(b, &t); err != nil {
+		return err
+	}
+	o.Value = &t
+	return nil
 }
 
 type guideResponse struct {
-	ID        string   `json:"id"`
-	CreatorID string   `json:"creator_id"`
-	Title     string   `json:"title"`
-	Content   string   `json:"content"`
-	Status    string   `json:"status"`
-	Tags      []tagDTO `json:"tags"`
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
+	ID          string       `json:"id"`
+	CreatorID   string       `json:"creator_id"`
+	Author      authorDTO    `json:"author"`
+	Title       string       `json:"title"`
+	Content     string       `json:"content"`
+	Format      string       `json:"format"`
+	Status      string       `json:"status"`
+	Version     int          `json:"version"`
+	Tags        []tagDTO     `json:"tags"`
+	GameVersion *string      `json:"game_version"`
+	Outdated    *outdatedDTO `json:"outdated"`
+	PublishAt   *string      `json:"publish_at"`
+	ArchiveAt   *string      `json:"archive_at"`
+	CreatedAt   string       `json:"created_at"`
+	UpdatedAt   string       `json:"updated_at"`
+}
+
+// outdatedDTO is the warning shown on guides an editor has flagged as stale.
+// It is null for guides that aren't flagged.
+type outdatedDTO struct {
+	Reason   string  `json:"reason"`
+	MarkedAt string  `json:"marked_at"`
+	MarkedBy *string `json:"marked_by"`
+}
+
+type authorDTO struct {
+	ID          string  `json:"id"`
+	DisplayName string  `json:"display_name"`
+	AvatarURL   *string `json:"avatar_url"`
 }
 
 type tagDTO struct {
@@ -48,14 +97,23 @@ type tagDTO struct {
 	Name string `json:"name"`
 }
 
+type tagFacetDTO struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
 type guideListItemResponse struct {
-	ID        string   `json:"id"`
-	CreatorID string   `json:"creator_id"`
-	Title     string   `json:"title"`
-	Status    string   `json:"status"`
-	Tags      []tagDTO `json:"tags"`
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
+	ID          string       `json:"id"`
+	CreatorID   string       `json:"creator_id"`
+	Author      authorDTO    `json:"author"`
+	Title       string       `json:"title"`
+	Status      string       `json:"status"`
+	Tags        []tagDTO     `json:"tags"`
+	GameVersion *string      `json:"game_version"`
+	Outdated    *outdatedDTO `json:"outdated"`
+	Snippet     string       `json:"snippet,omitempty"`
+	CreatedAt   string       `json:"created_at"`
+	UpdatedAt   string       `json:"updated_at"`
 }
 
 func (h *GuideHandler) Create(c *gin.Context) {
@@ -71,7 +129,10 @@ func (h *GuideHandler) Create(c *gin.Context) {
 		return
 	}
 
-	guideID, err := h.Guides.CreateGuide(c.Request.Context(), &currentUser, req.Title, req.Content, req.Tags)
+	guideID, err := h.Guides.CreateGuide(c.Request.Context(), &currentUser, req.Title, req.Content, req.GameVersion, req.Tags, store.GuideSchedule{
+		PublishAt: req.PublishAt,
+		ArchiveAt: req.ArchiveAt,
+	})
 	if err != nil {
 		writeServiceError(c, err)
 		return
@@ -93,19 +154,30 @@ func (h *GuideHandler) Update(c *gin.Context) {
 		return
 	}
 
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
 	var req updateGuideRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
 		return
 	}
 
-	err := h.Guides.UpdateGuide(c.Request.Context(), &currentUser, guideID, req.Title, req.Content, req.Tags)
+	version, err := h.Guides.UpdateGuide(c.Request.Context(), &currentUser, guideID, req.Title, req.Content, req.Tags, req.GameVersion, services.ScheduleChange{
+		PublishAt:    req.PublishAt.Value,
+		SetPublishAt: req.PublishAt.Set,
+		ArchiveAt:    req.ArchiveAt.Value,
+		SetArchiveAt: req.ArchiveAt.Set,
+	}, expectedVersion)
 	if err != nil {
 		writeServiceError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	c.Header("ETag", guideETag(version))
+	c.JSON(http.StatusOK, gin.H{"ok": true, "version": version})
 }
 
 func (h *GuideHandler) Publish(c *gin.Context) {
@@ -121,12 +193,19 @@ func (h *GuideHandler) Publish(c *gin.Context) {
 		return
 	}
 
-	if err := h.Guides.PublishGuide(c.Request.Context(), &currentUser, guideID); err != nil {
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.Guides.PublishGuide(c.Request.Context(), &currentUser, guideID, expectedVersion)
+	if err != nil {
 		writeServiceError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	c.Header("ETag", guideETag(version))
+	c.JSON(http.StatusOK, gin.H{"ok": true, "version": version})
 }
 
 func (h *GuideHandler) Unpublish(c *gin.Context) {
@@ -142,12 +221,47 @@ func (h *GuideHandler) Unpublish(c *gin.Context) {
 		return
 	}
 
-	if err := h.Guides.UnpublishGuide(c.Request.Context(), &currentUser, guideID); err != nil {
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.Guides.UnpublishGuide(c.Request.Context(), &currentUser, guideID, expectedVersion)
+	if err != nil {
 		writeServiceError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	c.Header("ETag", guideETag(version))
+	c.JSON(http.StatusOK, gin.H{"ok": true, "version": version})
+}
+
+func (h *GuideHandler) Archive(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	guideID := strings.TrimSpace(c.Param("id"))
+	if guideID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing guide id"})
+		return
+	}
+
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.Guides.ArchiveGuide(c.Request.Context(), &currentUser, guideID, expectedVersion)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", guideETag(version))
+	c.JSON(http.StatusOK, gin.H{"ok": true, "version": version})
 }
 
 func (h *GuideHandler) Delete(c *gin.Context) {
@@ -163,7 +277,12 @@ func (h *GuideHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.Guides.DeleteGuide(c.Request.Context(), &currentUser, guideID); err != nil {
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Guides.DeleteGuide(c.Request.Context(), &currentUser, guideID, expectedVersion); err != nil {
 		writeServiceError(c, err)
 		return
 	}
@@ -171,6 +290,8 @@ func (h *GuideHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
+// Get returns a guide with its content as markdown (the default) or, with
+// ?format=html, as sanitized HTML.
 func (h *GuideHandler) Get(c *gin.Context) {
 	guideID := strings.TrimSpace(c.Param("id"))
 	if guideID == "" {
@@ -178,6 +299,12 @@ func (h *GuideHandler) Get(c *gin.Context) {
 		return
 	}
 
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "markdown")))
+	if format != "markdown" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'markdown' or 'html'"})
+		return
+	}
+
 	var userPtr *store.User
 	if u, ok := getCurrentUser(c); ok {
 		userPtr = &u
@@ -189,32 +316,83 @@ func (h *GuideHandler) Get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, toGuideResponse(g))
+	resp := toGuideResponse(g)
+	if format == "html" {
+		resp.Content = g.ContentHTML
+		resp.Format = "html"
+	}
+
+	c.Header("ETag", guideETag(g.Version))
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *GuideHandler) ListPublished(c *gin.Context) {
-	tag := strings.TrimSpace(c.Query("tag"))
-	q := strings.TrimSpace(c.Query("q"))
+	params := store.ListGuidesParams{
+		Search:      strings.TrimSpace(c.Query("q")),
+		Tags:        splitList(c.Query("tags")),
+		ExcludeTags: splitList(c.Query("exclude")),
+		Limit:       parseIntDefault(c.Query("limit"), 20),
+		Offset:      parseIntDefault(c.Query("offset"), 0),
+
+		IncludeOutdated: c.Query("include_outdated") == "true",
+	}
+	if tag := strings.TrimSpace(c.Query("tag")); tag != "" {
+		params.Tags = append(params.Tags, tag)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(c.Query("match"))) {
+	case "", "all":
+		params.MatchAll = true
+	case "any":
+		params.MatchAll = false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match must be 'all' or 'any'"})
+		return
+	}
 
-	limit := parseIntDefault(c.Query("limit"), 20)
-	offset := parseIntDefault(c.Query("offset"), 0)
+	if token := strings.TrimSpace(c.Query("cursor")); token != "" {
+		cursor, err := store.DecodeGuideCursor(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		params.After = &cursor
+		params.Offset = 0
+	}
 
-	guides, err := h.Guides.ListPublishedGuides(c.Request.Context(), tag, q, limit, offset)
+	page, err := h.Guides.ListPublishedGuides(c.Request.Context(), params)
 	if err != nil {
 		writeServiceError(c, err)
 		return
 	}
 
-	out := make([]guideListItemResponse, 0, len(guides))
-	for _, g := range guides {
+	out := make([]guideListItemResponse, 0, len(page.Guides))
+	for _, g := range page.Guides {
 		out = append(out, toGuideListItemResponse(g))
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items":  out,
-		"limit":  limit,
-		"offset": offset,
-	})
+	var nextCursor *string
+	if page.HasMore && page.Next != nil {
+		token := page.Next.Encode()
+		nextCursor = &token
+	}
+
+	resp := gin.H{
+		"items":       out,
+		"limit":       params.Limit,
+		"offset":      params.Offset,
+		"next_cursor": nextCursor,
+		"has_more":    page.HasMore,
+	}
+	if params.After == nil {
+		facets := make([]tagFacetDTO, 0, len(page.Facets))
+		for _, f := range page.Facets {
+			facets = append(facets, tagFacetDTO{Name: f.Name, Count: f.Count})
+		}
+		resp["facets"] = facets
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 func getCurrentUser(c *gin.Context) (store.User, bool) {
@@ -233,6 +411,16 @@ func getCurrentUser(c *gin.Context) (store.User, bool) {
 }
 
 func writeServiceError(c *gin.Context, err error) {
+	var conflict *store.VersionConflictError
+	if errors.As(err, &conflict) {
+		c.Header("ETag", guideETag(conflict.Current))
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"error":           "guide was modified by someone else",
+			"current_version": conflict.Current,
+		})
+		return
+	}
+
 	switch err {
 	case services.ErrUnauthenticated:
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
@@ -242,11 +430,42 @@ func writeServiceError(c *gin.Context, err error) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 	case services.ErrInvalidInput:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+	case services.ErrConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": "conflict"})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
 }
 
+func guideETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// requireIfMatch reads the guide version the caller last saw from If-Match.
+// Writes without it are rejected so concurrent edits can't clobber each other.
+func requireIfMatch(c *gin.Context) (int, bool) {
+	v := strings.TrimSpace(c.GetHeader("If-Match"))
+	v = strings.TrimPrefix(v, "W/")
+	v = strings.Trim(v, `"`)
+
+	version, err := strconv.Atoi(v)
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the guide ETag is required"})
+		return 0, false
+	}
+	return version, true
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 func parseIntDefault(v string, def int) int {
 	if strings.TrimSpace(v) == "" {
 		return def
@@ -265,17 +484,43 @@ func toGuideResponse(g store.Guide) guideResponse {
 	}
 
 	return guideResponse{
-		ID:        g.ID,
-		CreatorID: g.CreatorID,
-		Title:     g.Title,
-		Content:   g.Content,
-		Status:    g.Status,
-		Tags:      tags,
-		CreatedAt: g.CreatedAt.Format(timeRFC3339()),
-		UpdatedAt: g.UpdatedAt.Format(timeRFC3339()),
+		ID:          g.ID,
+		CreatorID:   g.CreatorID,
+		Author:      toAuthorDTO(g.Author),
+		Title:       g.Title,
+		Content:     g.Content,
+		Format:      "markdown",
+		Status:      g.Status,
+		Version:     g.Version,
+		Tags:        tags,
+		GameVersion: g.GameVersion,
+		Outdated:    toOutdatedDTO(g.Outdated),
+		PublishAt:   formatOptionalTime(g.PublishAt),
+		ArchiveAt:   formatOptionalTime(g.ArchiveAt),
+		CreatedAt:   g.CreatedAt.Format(timeRFC3339()),
+		UpdatedAt:   g.UpdatedAt.Format(timeRFC3339()),
+	}
+}
+
+func toOutdatedDTO(o *store.GuideOutdated) *outdatedDTO {
+	if o == nil {
+		return nil
+	}
+	return &outdatedDTO{
+		Reason:   o.Reason,
+		MarkedAt: o.MarkedAt.Format(timeRFC3339()),
+		MarkedBy: o.MarkedBy,
 	}
 }
 
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	v := t.Format(timeRFC3339())
+	return &v
+}
+
 func toGuideListItemResponse(g store.Guide) guideListItemResponse {
 	tags := make([]tagDTO, 0, len(g.Tags))
 	for _, t := range g.Tags {
@@ -283,13 +528,25 @@ func toGuideListItemResponse(g store.Guide) guideListItemResponse {
 	}
 
 	return guideListItemResponse{
-		ID:        g.ID,
-		CreatorID: g.CreatorID,
-		Title:     g.Title,
-		Status:    g.Status,
-		Tags:      tags,
-		CreatedAt: g.CreatedAt.Format(timeRFC3339()),
-		UpdatedAt: g.UpdatedAt.Format(timeRFC3339()),
+		ID:          g.ID,
+		CreatorID:   g.CreatorID,
+		Author:      toAuthorDTO(g.Author),
+		Title:       g.Title,
+		Status:      g.Status,
+		Tags:        tags,
+		GameVersion: g.GameVersion,
+		Outdated:    toOutdatedDTO(g.Outdated),
+		Snippet:     g.Headline,
+		CreatedAt:   g.CreatedAt.Format(timeRFC3339()),
+		UpdatedAt:   g.UpdatedAt.Format(timeRFC3339()),
+	}
+}
+
+func toAuthorDTO(a store.GuideAuthor) authorDTO {
+	return authorDTO{
+		ID:          a.ID,
+		DisplayName: a.DisplayName,
+		AvatarURL:   a.AvatarURL,
 	}
 }
 
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"skyhow/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IdentityHandler struct {
+	AuthService *services.AuthService
+}
+
+func NewIdentityHandler(authSvc *services.AuthService) *IdentityHandler {
+	return &IdentityHandler{AuthService: authSvc}
+}
+
+type identityResponse struct {
+	ID             string  `json:"id"`
+	Provider       string  `json:"provider"`
+	ProviderUserID string  `json:"provider_user_id"`
+	Email          *string `json:"email"`
+	CreatedAt      string  `json:"created_at"`
+	LastLoginAt    *string `json:"last_login_at"`
+}
+
+func (h *IdentityHandler) List(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	identities, err := h.AuthService.ListIdentities(c.Request.Context(), &currentUser)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	out := make([]identityResponse, 0, len(identities))
+	for _, i := range identities {
+		var lastLogin *string
+		if i.LastLoginAt != nil {
+			v := i.LastLoginAt.Format(timeRFC3339())
+			lastLogin = &v
+		}
+		out = append(out, identityResponse{
+			ID:             i.ID,
+			Provider:       i.Provider,
+			ProviderUserID: i.ProviderUserID,
+			Email:          i.Email,
+			CreatedAt:      i.CreatedAt.Format(timeRFC3339()),
+			LastLoginAt:    lastLogin,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": out})
+}
+
+func (h *IdentityHandler) Unlink(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	identityID := strings.TrimSpace(c.Param("id"))
+	if identityID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing identity id"})
+		return
+	}
+
+	if err := h.AuthService.UnlinkIdentity(c.Request.Context(), &currentUser, identityID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
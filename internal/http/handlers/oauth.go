@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"skyhow/internal/auth"
+	"skyhow/internal/services"
+	"skyhow/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthHandler struct {
+	Sessions    *store.SessionStore
+	AuthService *services.AuthService
+
+	CookieSecure bool
+	CookieDomain string
+}
+
+const (
+	oauthStateCookie  = "sb_oauth_state"
+	sessionCookieName = "sb_session"
+)
+
+func NewOAuthHandler(
+	sessions *store.SessionStore,
+	authSvc *services.AuthService,
+	cookieSecure bool,
+	cookieDomain string,
+) *OAuthHandler {
+	return &OAuthHandler{
+		Sessions:     sessions,
+		AuthService:  authSvc,
+		CookieSecure: cookieSecure,
+		CookieDomain: cookieDomain,
+	}
+}
+
+func (h *OAuthHandler) Start(c *gin.Context) {
+	h.start(c, false)
+}
+
+// Link starts the OAuth flow for attaching another provider account to the
+// logged-in user instead of logging in.
+func (h *OAuthHandler) Link(c *gin.Context) {
+	if _, ok := getCurrentUser(c); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	h.start(c, true)
+}
+
+// start records the login attempt server-side and binds its state to this
+// browser with a short-lived cookie.
+func (h *OAuthHandler) start(c *gin.Context, link bool) {
+	if _, ok := h.provider(c); !ok {
+		return
+	}
+
+	var linkUser *store.User
+	if link {
+		u, _ := getCurrentUser(c)
+		linkUser = &u
+	}
+
+	authURL, state, err := h.AuthService.BeginLogin(c.Request.Context(), c.Param("provider"), c.Query("returnTo"), linkUser)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	h.setCookie(c, oauthStateCookie, state, 600)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// provider looks up the :provider route param and writes a 404 when it isn't
+// configured.
+func (h *OAuthHandler) provider(c *gin.Context) (auth.Provider, bool) {
+	if h.AuthService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "auth service not configured"})
+		return nil, false
+	}
+	p, ok := h.AuthService.Provider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return nil, false
+	}
+	return p, true
+}
+
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	provider, ok := h.provider(c)
+	if !ok {
+		return
+	}
+
+	expectedState, err := c.Cookie(oauthStateCookie)
+	if err != nil || expectedState == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing oauth state cookie"})
+		return
+	}
+
+	gotState := c.Query("state")
+	if gotState == "" || subtle.ConstantTimeCompare([]byte(gotState), []byte(expectedState)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oauth state"})
+		return
+	}
+
+	h.setCookie(c, oauthStateCookie, "", -1)
+
+	// Consuming the state before looking at the code means a replayed
+	// callback fails even if the provider would accept the code again.
+	st, err := h.AuthService.ConsumeState(c.Request.Context(), provider.Name(), gotState)
+	if err != nil {
+		if err == services.ErrInvalidInput {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oauth state"})
+			return
+		}
+		writeServiceError(c, err)
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing code"})
+		return
+	}
+
+	if st.LinkUserID != nil {
+		currentUser, ok := getCurrentUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+			return
+		}
+		if err := h.AuthService.Link(c.Request.Context(), &currentUser, st, code); err != nil {
+			writeServiceError(c, err)
+			return
+		}
+		c.Redirect(http.StatusFound, auth.SafeReturnTo(st.ReturnTo))
+		return
+	}
+
+	sessionID, expiresAt, err := h.AuthService.Login(c.Request.Context(), st, code, store.SessionMeta{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if err != nil {
+		if err == services.ErrForbidden {
+			writeServiceError(c, err)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.setCookie(c, sessionCookieName, sessionID, int(time.Until(expiresAt).Seconds()))
+
+	c.Redirect(http.StatusFound, auth.SafeReturnTo(st.ReturnTo))
+}
+
+func (h *OAuthHandler) Logout(c *gin.Context) {
+	sessionID, _ := c.Cookie(sessionCookieName)
+
+	if h.AuthService != nil {
+		_ = h.AuthService.Logout(c.Request.Context(), sessionID)
+	} else if h.Sessions != nil && sessionID != "" {
+		_, _ = h.Sessions.Delete(c.Request.Context(), sessionID)
+	}
+
+	h.setCookie(c, sessionCookieName, "", -1)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// setCookie writes an HttpOnly cookie with SameSite=Lax. Lax still sends the
+// cookie on the top-level redirect back from the provider.
+func (h *OAuthHandler) setCookie(c *gin.Context, name, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, "/", h.CookieDomain, h.CookieSecure, true)
+}
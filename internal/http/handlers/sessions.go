@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"skyhow/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	AuthService *services.AuthService
+}
+
+func NewSessionHandler(authSvc *services.AuthService) *SessionHandler {
+	return &SessionHandler{AuthService: authSvc}
+}
+
+type sessionResponse struct {
+	ID         string  `json:"id"`
+	Current    bool    `json:"current"`
+	UserAgent  *string `json:"user_agent"`
+	IP         *string `json:"ip"`
+	CreatedAt  string  `json:"created_at"`
+	LastSeenAt string  `json:"last_seen_at"`
+	ExpiresAt  string  `json:"expires_at"`
+}
+
+func (h *SessionHandler) List(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+	currentSessionID := c.GetString("session_id")
+
+	sessions, err := h.AuthService.ListSessions(c.Request.Context(), &currentUser)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	out := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, sessionResponse{
+			ID:         s.PublicID,
+			Current:    s.ID == currentSessionID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt.Format(timeRFC3339()),
+			LastSeenAt: s.LastSeenAt.Format(timeRFC3339()),
+			ExpiresAt:  s.ExpiresAt.Format(timeRFC3339()),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": out})
+}
+
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	publicID := strings.TrimSpace(c.Param("id"))
+	if publicID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing session id"})
+		return
+	}
+
+	if err := h.AuthService.RevokeSession(c.Request.Context(), &currentUser, publicID); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *SessionHandler) RevokeOthers(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	revoked, err := h.AuthService.RevokeOtherSessions(c.Request.Context(), &currentUser, c.GetString("session_id"))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "revoked": revoked})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"skyhow/internal/services"
+	"skyhow/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	Tags *services.TagService
+}
+
+func NewTagHandler(tags *services.TagService) *TagHandler {
+	return &TagHandler{Tags: tags}
+}
+
+type tagUsageResponse struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Description    *string `json:"description"`
+	Category       *string `json:"category"`
+	Color          *string `json:"color"`
+	PublishedCount int     `json:"published_count"`
+}
+
+type tagDetailsResponse struct {
+	tagUsageResponse
+	Aliases []string `json:"aliases"`
+}
+
+type updateTagRequest struct {
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Color       string `json:"color"`
+}
+
+type addAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+type mergeTagRequest struct {
+	Into string `json:"into"`
+}
+
+func (h *TagHandler) List(c *gin.Context) {
+	limit := parseIntDefault(c.Query("limit"), 50)
+	offset := parseIntDefault(c.Query("offset"), 0)
+
+	tags, err := h.Tags.ListTags(c.Request.Context(), limit, offset)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  toTagUsageResponses(tags),
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *TagHandler) Suggest(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("prefix"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing prefix"})
+		return
+	}
+
+	tags, err := h.Tags.SuggestTags(c.Request.Context(), prefix, parseIntDefault(c.Query("limit"), 10))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": toTagUsageResponses(tags)})
+}
+
+func (h *TagHandler) Get(c *gin.Context) {
+	t, err := h.Tags.GetTag(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	aliases := t.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	c.JSON(http.StatusOK, tagDetailsResponse{
+		tagUsageResponse: toTagUsageResponse(t.TagUsage),
+		Aliases:          aliases,
+	})
+}
+
+func (h *TagHandler) Update(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req updateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	err := h.Tags.UpdateTagMetadata(c.Request.Context(), &currentUser, c.Param("name"), req.Description, req.Category, req.Color)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *TagHandler) AddAlias(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req addAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	if err := h.Tags.AddAlias(c.Request.Context(), &currentUser, c.Param("name"), req.Alias); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"ok": true})
+}
+
+func (h *TagHandler) RemoveAlias(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	if err := h.Tags.RemoveAlias(c.Request.Context(), &currentUser, c.Param("name"), c.Param("alias")); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *TagHandler) Merge(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req mergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	retagged, err := h.Tags.MergeTags(c.Request.Context(), &currentUser, c.Param("name"), req.Into)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "retagged_guides": retagged})
+}
+
+func toTagUsageResponses(tags []store.TagUsage) []tagUsageResponse {
+	out := make([]tagUsageResponse, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, toTagUsageResponse(t))
+	}
+	return out
+}
+
+func toTagUsageResponse(t store.TagUsage) tagUsageResponse {
+	return tagUsageResponse{
+		ID:             t.ID,
+		Name:           t.Name,
+		Description:    t.Description,
+		Category:       t.Category,
+		Color:          t.Color,
+		PublishedCount: t.PublishedCount,
+	}
+}
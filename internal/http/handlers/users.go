@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"skyhow/internal/services"
+	"skyhow/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserAdminHandler struct {
+	Users *services.UserService
+}
+
+func NewUserAdminHandler(users *services.UserService) *UserAdminHandler {
+	return &UserAdminHandler{Users: users}
+}
+
+type adminUserResponse struct {
+	ID            string  `json:"id"`
+	DisplayName   string  `json:"display_name"`
+	AvatarURL     *string `json:"avatar_url"`
+	Email         *string `json:"email"`
+	EmailVerified bool    `json:"email_verified"`
+	Role          string  `json:"role"`
+	IsActive      bool    `json:"is_active"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+type changeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *UserAdminHandler) List(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	params := store.ListUsersParams{
+		Search: c.Query("q"),
+		Role:   strings.TrimSpace(c.Query("role")),
+		Limit:  parseIntDefault(c.Query("limit"), 50),
+		Offset: parseIntDefault(c.Query("offset"), 0),
+	}
+	switch c.Query("active") {
+	case "":
+	case "true":
+		active := true
+		params.Active = &active
+	case "false":
+		active := false
+		params.Active = &active
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "active must be true or false"})
+		return
+	}
+
+	users, err := h.Users.ListUsers(c.Request.Context(), &currentUser, params)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	out := make([]adminUserResponse, 0, len(users))
+	for _, u := range users {
+		out = append(out, toAdminUserResponse(u))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":  out,
+		"limit":  params.Limit,
+		"offset": params.Offset,
+	})
+}
+
+func (h *UserAdminHandler) Get(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID := strings.TrimSpace(c.Param("id"))
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing user id"})
+		return
+	}
+
+	u, guides, err := h.Users.GetUser(c.Request.Context(), &currentUser, userID)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	items := make([]guideListItemResponse, 0, len(guides))
+	for _, g := range guides {
+		items = append(items, toGuideListItemResponse(g))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":   toAdminUserResponse(u),
+		"guides": items,
+	})
+}
+
+func (h *UserAdminHandler) ChangeRole(c *gin.Context) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID := strings.TrimSpace(c.Param("id"))
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing user id"})
+		return
+	}
+
+	var req changeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		return
+	}
+
+	if err := h.Users.ChangeRole(c.Request.Context(), &currentUser, userID, strings.TrimSpace(req.Role)); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *UserAdminHandler) Deactivate(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *UserAdminHandler) Reactivate(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *UserAdminHandler) setActive(c *gin.Context, active bool) {
+	currentUser, ok := getCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID := strings.TrimSpace(c.Param("id"))
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing user id"})
+		return
+	}
+
+	if err := h.Users.SetUserActive(c.Request.Context(), &currentUser, userID, active); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func toAdminUserResponse(u store.User) adminUserResponse {
+	return adminUserResponse{
+		ID:            u.ID,
+		DisplayName:   u.DisplayName,
+		AvatarURL:     u.AvatarURL,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Role:          u.Role,
+		IsActive:      u.IsActive,
+		CreatedAt:     u.CreatedAt.Format(timeRFC3339()),
+	}
+}
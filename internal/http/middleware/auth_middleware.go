@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
+	"slices"
+	"time"
 
+	"skyhow/internal/authz"
 	"skyhow/internal/store"
 
 	"github.com/gin-gonic/gin"
@@ -11,9 +15,15 @@ import (
 
 const sessionCookieName = "sb_session"
 
+// lastSeenTouchInterval bounds how often a session's last_seen_at is written.
+const lastSeenTouchInterval = 5 * time.Minute
+
+// AuthMiddleware resolves the session cookie to a user. Sessions with less
+// than half of sessionTTL left are renewed and the cookie is re-issued.
 func AuthMiddleware(
 	users *store.UserStore,
 	sessions *store.SessionStore,
+	sessionTTL time.Duration,
 	cookieDomain string,
 	cookieSecure bool,
 ) gin.HandlerFunc {
@@ -24,19 +34,19 @@ func AuthMiddleware(
 			return
 		}
 
-		userID, err := sessions.GetUserIDBySessionID(c.Request.Context(), sessionID)
+		sess, err := sessions.Get(c.Request.Context(), sessionID)
 		if err != nil {
 			clearSessionCookie(c, cookieDomain, cookieSecure)
 			c.Next()
 			return
 		}
-		if userID == "" {
+		if sess.UserID == "" {
 			clearSessionCookie(c, cookieDomain, cookieSecure)
 			c.Next()
 			return
 		}
 
-		u, err := users.GetByID(c.Request.Context(), userID)
+		u, err := users.GetByID(c.Request.Context(), sess.UserID)
 		if err != nil {
 			if err == pgx.ErrNoRows {
 				clearSessionCookie(c, cookieDomain, cookieSecure)
@@ -51,6 +61,22 @@ func AuthMiddleware(
 		}
 
 		c.Set("user", u)
+		c.Set("session_id", sess.ID)
+
+		if sessionTTL > 0 && time.Until(sess.ExpiresAt) < sessionTTL/2 {
+			expiresAt, renewed, err := sessions.Renew(c.Request.Context(), sess.ID, sessionTTL)
+			if err != nil {
+				log.Println("session renew:", err)
+			} else if renewed {
+				setSessionCookie(c, sessionID, expiresAt, cookieDomain, cookieSecure)
+			}
+		}
+
+		if time.Since(sess.LastSeenAt) > lastSeenTouchInterval {
+			if err := sessions.Touch(c.Request.Context(), sess.ID, c.ClientIP(), lastSeenTouchInterval); err != nil {
+				log.Println("session touch:", err)
+			}
+		}
 
 		c.Header("Cache-Control", "no-store")
 
@@ -60,7 +86,13 @@ func AuthMiddleware(
 	}
 }
 
+func setSessionCookie(c *gin.Context, sessionID string, expiresAt time.Time, cookieDomain string, cookieSecure bool) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName, sessionID, int(time.Until(expiresAt).Seconds()), "/", cookieDomain, cookieSecure, true)
+}
+
 func clearSessionCookie(c *gin.Context, cookieDomain string, cookieSecure bool) {
+	c.SetSameSite(http.SameSiteLaxMode)
 	c.SetCookie(sessionCookieName, "", -1, "/", cookieDomain, cookieSecure, true)
 }
 
@@ -76,3 +108,34 @@ func RequireAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequirePermission lets the request through only when the logged-in user's
+// role grants p.
+func RequirePermission(p authz.Permission) gin.HandlerFunc {
+	return RequireAnyPermission(p)
+}
+
+// RequireAnyPermission lets the request through when the logged-in user's
+// role grants at least one of ps.
+func RequireAnyPermission(ps ...authz.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uAny, ok := c.Get("user")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "not authenticated",
+			})
+			c.Abort()
+			return
+		}
+
+		u, ok := uAny.(store.User)
+		if !ok || !slices.ContainsFunc(ps, func(p authz.Permission) bool { return authz.Can(u.Role, p) }) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "forbidden",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCSRFTestRouter(trustedOrigins []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CSRF(trustedOrigins, "", false))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.GET("/thing", ok)
+	r.HEAD("/thing", ok)
+	r.OPTIONS("/thing", ok)
+	r.POST("/thing", ok)
+	r.PUT("/thing", ok)
+	r.DELETE("/thing", ok)
+	return r
+}
+
+func TestCSRFSafeMethods(t *testing.T) {
+	r := newCSRFTestRouter(nil)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "http://api.example/thing", nil)
+			req.Header.Set("Origin", "https://evil.example")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("got %d, want 204", w.Code)
+			}
+			if !hasCookie(w.Result(), csrfCookieName) {
+				t.Fatal("safe request without a token didn't get a csrf cookie")
+			}
+		})
+	}
+}
+
+func TestCSRFUnsafeMethods(t *testing.T) {
+	const token = "known-token"
+
+	tests := []struct {
+		name    string
+		method  string
+		trusted []string
+		cookie  string
+		header  string
+		origin  string
+		referer string
+		want    int
+	}{
+		{name: "matching token, no origin", method: http.MethodPost, cookie: token, header: token, want: http.StatusNoContent},
+		{name: "matching token, same origin", method: http.MethodPut, cookie: token, header: token, origin: "http://api.example", want: http.StatusNoContent},
+		{name: "origin host is case insensitive", method: http.MethodPost, cookie: token, header: token, origin: "http://API.example", want: http.StatusNoContent},
+		{name: "missing header", method: http.MethodPost, cookie: token, want: http.StatusForbidden},
+		{name: "mismatched header", method: http.MethodDelete, cookie: token, header: "other-token", want: http.StatusForbidden},
+		{name: "no cookie yet", method: http.MethodPost, header: token, want: http.StatusForbidden},
+		{name: "cross origin", method: http.MethodPost, cookie: token, header: token, origin: "https://evil.example", want: http.StatusForbidden},
+		{name: "null origin", method: http.MethodPost, cookie: token, header: token, origin: "null", want: http.StatusForbidden},
+		{name: "referer fallback, same host", method: http.MethodPost, cookie: token, header: token, referer: "http://api.example/guides/1", want: http.StatusNoContent},
+		{name: "referer fallback, cross origin", method: http.MethodPost, cookie: token, header: token, referer: "https://evil.example/page", want: http.StatusForbidden},
+		{name: "relative referer", method: http.MethodPost, cookie: token, header: token, referer: "/guides/1", want: http.StatusForbidden},
+		{name: "origin wins over referer", method: http.MethodPost, cookie: token, header: token, origin: "https://evil.example", referer: "http://api.example/", want: http.StatusForbidden},
+		{
+			name:    "trusted origin",
+			method:  http.MethodPost,
+			trusted: []string{" https://app.example/ "},
+			cookie:  token,
+			header:  token,
+			origin:  "https://App.example",
+			want:    http.StatusNoContent,
+		},
+		{
+			name:    "trusted origin via referer",
+			method:  http.MethodPost,
+			trusted: []string{"https://app.example"},
+			cookie:  token,
+			header:  token,
+			referer: "https://app.example/guides/new",
+			want:    http.StatusNoContent,
+		},
+		{
+			name:    "own host isn't trusted when origins are configured",
+			method:  http.MethodPost,
+			trusted: []string{"https://app.example"},
+			cookie:  token,
+			header:  token,
+			origin:  "http://api.example",
+			want:    http.StatusForbidden,
+		},
+		{
+			name:    "trusted origin still needs the token",
+			method:  http.MethodPost,
+			trusted: []string{"https://app.example"},
+			cookie:  token,
+			header:  "other-token",
+			origin:  "https://app.example",
+			want:    http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newCSRFTestRouter(tt.trusted)
+
+			req := httptest.NewRequest(tt.method, "http://api.example/thing", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeaderName, tt.header)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func hasCookie(resp *http.Response, name string) bool {
+	for _, c := range resp.Cookies() {
+		if c.Name == name && c.Value != "" {
+			return true
+		}
+	}
+	return false
+}
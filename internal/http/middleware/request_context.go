@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"skyhow/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIP puts the client's IP on the request context, where services pick
+// it up for audit events.
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(services.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
@@ -2,34 +2,61 @@ package http
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"skyhow/internal/authz"
 	"skyhow/internal/http/handlers"
 	"skyhow/internal/http/middleware"
 	"skyhow/internal/store"
 )
 
 type RouterDeps struct {
-	DiscordAuth  *handlers.DiscordAuthHandler
+	OAuth        *handlers.OAuthHandler
 	Guides       *handlers.GuideHandler
+	Tags         *handlers.TagHandler
+	UserSessions *handlers.SessionHandler
+	Identities   *handlers.IdentityHandler
+	AdminUsers   *handlers.UserAdminHandler
+	Audit        *handlers.AuditHandler
 	Users        *store.UserStore
 	Sessions     *store.SessionStore
+	SessionTTL   time.Duration
 	CookieSecure bool
 	CookieDomain string
+	// TrustedOrigins may send unsafe requests. Empty means same host only.
+	TrustedOrigins []string
+	// TrustedProxies are the addresses or CIDRs whose X-Forwarded-For is
+	// believed when working out the client IP. Empty means none.
+	TrustedProxies []string
 }
 
-func NewRouter(deps RouterDeps) *gin.Engine {
+func NewRouter(deps RouterDeps) (*gin.Engine, error) {
 	r := gin.New()
 
+	var proxies []string
+	for _, p := range deps.TrustedProxies {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		return nil, err
+	}
+
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
+	r.Use(middleware.ClientIP())
 
 	r.Use(middleware.AuthMiddleware(
 		deps.Users,
 		deps.Sessions,
+		deps.SessionTTL,
 		deps.CookieDomain,
 		deps.CookieSecure,
 	))
+	r.Use(middleware.CSRF(deps.TrustedOrigins, deps.CookieDomain, deps.CookieSecure))
 
 	r.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"ok": true})
@@ -37,9 +64,10 @@ func NewRouter(deps RouterDeps) *gin.Engine {
 
 	auth := r.Group("/auth")
 	{
-		auth.GET("/discord/start", deps.DiscordAuth.Start)
-		auth.GET("/discord/callback", deps.DiscordAuth.Callback)
-		auth.POST("/logout", deps.DiscordAuth.Logout)
+		auth.GET("/:provider/start", deps.OAuth.Start)
+		auth.GET("/:provider/link", middleware.RequireAuth(), deps.OAuth.Link)
+		auth.GET("/:provider/callback", deps.OAuth.Callback)
+		auth.POST("/logout", deps.OAuth.Logout)
 	}
 
 	api := r.Group("/api")
@@ -47,19 +75,71 @@ func NewRouter(deps RouterDeps) *gin.Engine {
 	guides := api.Group("/guides")
 	{
 		guides.GET("", deps.Guides.ListPublished)
+		guides.GET("/review-queue", middleware.RequireAuth(), middleware.RequirePermission(authz.GuidesReview), deps.Guides.ReviewQueue)
 		guides.GET("/:id", deps.Guides.Get)
 
-		guides.POST("", middleware.RequireAuth(), deps.Guides.Create)
+		guides.POST("", middleware.RequireAuth(), middleware.RequirePermission(authz.GuidesCreate), deps.Guides.Create)
 		guides.PUT("/:id", middleware.RequireAuth(), deps.Guides.Update)
-		guides.POST("/:id/publish", middleware.RequireAuth(), deps.Guides.Publish)
+		guides.POST("/:id/publish", middleware.RequireAuth(), middleware.RequirePermission(authz.GuidesPublish), deps.Guides.Publish)
 		guides.POST("/:id/unpublish", middleware.RequireAuth(), deps.Guides.Unpublish)
+		guides.POST("/:id/archive", middleware.RequireAuth(), middleware.RequireAnyPermission(authz.GuidesPublish, authz.GuidesEditAny), deps.Guides.Archive)
 		guides.DELETE("/:id", middleware.RequireAuth(), deps.Guides.Delete)
+
+		guides.POST("/:id/submit", middleware.RequireAuth(), middleware.RequirePermission(authz.GuidesSubmit), deps.Guides.Submit)
+		guides.POST("/:id/approve", middleware.RequireAuth(), middleware.RequirePermission(authz.GuidesReview), deps.Guides.Approve)
+		guides.POST("/:id/request-changes", middleware.RequireAuth(), middleware.RequirePermission(authz.GuidesReview), deps.Guides.RequestChanges)
+		guides.GET("/:id/reviews", middleware.RequireAuth(), deps.Guides.ListReviews)
+
+		guides.PUT("/:id/outdated", middleware.RequireAuth(), middleware.RequirePermission(authz.GuidesMarkOutdated), deps.Guides.MarkOutdated)
+		guides.DELETE("/:id/outdated", middleware.RequireAuth(), middleware.RequirePermission(authz.GuidesMarkOutdated), deps.Guides.ClearOutdated)
+
+		guides.GET("/:id/revisions", middleware.RequireAuth(), deps.Guides.ListRevisions)
+		guides.GET("/:id/revisions/:rev", middleware.RequireAuth(), deps.Guides.GetRevision)
+		guides.GET("/:id/revisions/:rev/diff", middleware.RequireAuth(), deps.Guides.DiffRevision)
+		guides.POST("/:id/revisions/:rev/restore", middleware.RequireAuth(), deps.Guides.RestoreRevision)
+	}
+
+	tags := api.Group("/tags")
+	{
+		tags.GET("", deps.Tags.List)
+		tags.GET("/suggest", middleware.RequireAuth(), deps.Tags.Suggest)
+		tags.GET("/:name", deps.Tags.Get)
+
+		tags.PUT("/:name", middleware.RequireAuth(), middleware.RequirePermission(authz.TagsCurate), deps.Tags.Update)
+		tags.POST("/:name/aliases", middleware.RequireAuth(), middleware.RequirePermission(authz.TagsManage), deps.Tags.AddAlias)
+		tags.DELETE("/:name/aliases/:alias", middleware.RequireAuth(), middleware.RequirePermission(authz.TagsManage), deps.Tags.RemoveAlias)
+		tags.POST("/:name/merge", middleware.RequireAuth(), middleware.RequirePermission(authz.TagsManage), deps.Tags.Merge)
+	}
+
+	me := api.Group("/me", middleware.RequireAuth())
+	{
+		me.GET("/sessions", deps.UserSessions.List)
+		me.DELETE("/sessions", deps.UserSessions.RevokeOthers)
+		me.DELETE("/sessions/:id", deps.UserSessions.Revoke)
+
+		me.GET("/identities", deps.Identities.List)
+		me.DELETE("/identities/:id", deps.Identities.Unlink)
+	}
+
+	admin := api.Group("/admin", middleware.RequireAuth())
+	{
+		users := admin.Group("/users", middleware.RequirePermission(authz.UsersManage))
+		users.GET("", deps.AdminUsers.List)
+		users.GET("/:id", deps.AdminUsers.Get)
+		users.PUT("/:id/role", deps.AdminUsers.ChangeRole)
+		users.POST("/:id/deactivate", deps.AdminUsers.Deactivate)
+		users.POST("/:id/reactivate", deps.AdminUsers.Reactivate)
+
+		admin.GET("/audit", middleware.RequirePermission(authz.AuditRead), deps.Audit.List)
 	}
 
 	r.GET("/me", func(c *gin.Context) {
 		uAny, ok := c.Get("user")
 		if !ok || uAny == nil {
-			c.JSON(http.StatusOK, gin.H{"authenticated": false})
+			c.JSON(http.StatusOK, gin.H{
+				"authenticated": false,
+				"csrf_token":    c.GetString("csrf_token"),
+			})
 			return
 		}
 
@@ -67,14 +147,16 @@ func NewRouter(deps RouterDeps) *gin.Engine {
 
 		c.JSON(http.StatusOK, gin.H{
 			"authenticated": true,
+			"csrf_token":    c.GetString("csrf_token"),
 			"user": gin.H{
 				"id":           u.ID,
 				"display_name": u.DisplayName,
 				"avatar_url":   u.AvatarURL,
 				"role":         u.Role,
 			},
+			"permissions": authz.Permissions(u.Role),
 		})
 	})
 
-	return r
+	return r, nil
 }
@@ -0,0 +1,33 @@
+// Package markdown renders guide content to HTML that is safe to embed in a
+// page as-is.
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// renderer speaks CommonMark plus GFM tables. Raw HTML in the source is
+// dropped rather than passed through, and goldmark already blanks out
+// dangerous link schemes such as javascript:.
+var renderer = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+	),
+)
+
+// policy sanitizes the rendered HTML anyway, so a renderer bug or a future
+// extension can't reintroduce scripts, event handlers or unsafe URLs.
+var policy = bluemonday.UGCPolicy()
+
+// Render converts markdown source to sanitized HTML.
+func Render(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
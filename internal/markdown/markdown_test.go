@@ -0,0 +1,113 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// want must appear in the output and none of reject may.
+		want   []string
+		reject []string
+	}{
+		{
+			name:   "javascript link",
+			src:    "[click](javascript:alert(1))",
+			want:   []string{"click"},
+			reject: []string{"javascript:", "href"},
+		},
+		{
+			name:   "mixed case javascript link",
+			src:    "[click](JaVaScRiPt:alert(1))",
+			want:   []string{"click"},
+			reject: []string{"alert", "href"},
+		},
+		{
+			name:   "javascript autolink",
+			src:    "<javascript:alert(1)>",
+			reject: []string{"href"},
+		},
+		{
+			name:   "raw html javascript link",
+			src:    `<a href="javascript:alert(1)">x</a>`,
+			reject: []string{"href", "<a"},
+		},
+		{
+			name:   "raw script",
+			src:    "<script>alert(1)</script>\n\nafter",
+			want:   []string{"<p>after</p>"},
+			reject: []string{"<script", "alert(1)"},
+		},
+		{
+			name:   "inline script",
+			src:    "before <script>alert(1)</script> after",
+			reject: []string{"<script"},
+		},
+		{
+			name:   "onerror attribute",
+			src:    "hi <img src=x onerror=alert(1)> there",
+			want:   []string{"hi"},
+			reject: []string{"onerror", "<img"},
+		},
+		{
+			name:   "javascript image",
+			src:    "![pic](javascript:alert(1))",
+			reject: []string{"javascript:", "src="},
+		},
+		{
+			name:   "data image",
+			src:    "![pic](data:image/svg+xml;base64,PHN2ZyBvbmxvYWQ9YWxlcnQoMSk+)",
+			reject: []string{"data:", "src="},
+		},
+		{
+			name:   "data link",
+			src:    "[x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)",
+			reject: []string{"data:", "href"},
+		},
+		{
+			name: "safe link",
+			src:  "[wiki](https://wiki.hypixel.net/)",
+			want: []string{`<a href="https://wiki.hypixel.net/" rel="nofollow">wiki</a>`},
+		},
+		{
+			name: "emphasis and code",
+			src:  "**bold** and `code`",
+			want: []string{"<strong>bold</strong>", "<code>code</code>"},
+		},
+		{
+			name: "table alignment",
+			src:  "| left | center | right |\n|:--|:-:|--:|\n| a | b | c |",
+			want: []string{
+				"<table>",
+				`<th align="left">left</th>`,
+				`<th align="center">center</th>`,
+				`<th align="right">right</th>`,
+				`<td align="left">a</td>`,
+				`<td align="right">c</td>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.src)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			lower := strings.ToLower(got)
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("output %q doesn't contain %q", got, w)
+				}
+			}
+			for _, r := range tt.reject {
+				if strings.Contains(lower, strings.ToLower(r)) {
+					t.Errorf("output %q contains %q", got, r)
+				}
+			}
+		})
+	}
+}
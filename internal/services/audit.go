@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"skyhow/internal/authz"
+	"skyhow/internal/store"
+)
+
+type clientIPKey struct{}
+
+// WithClientIP attaches the requesting client's IP to ctx so audit events can
+// record where an action came from.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func clientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// AuditEntry describes an action for the audit log.
+type AuditEntry struct {
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     map[string]any
+	After      map[string]any
+}
+
+type AuditService struct {
+	Events *store.AuditStore
+}
+
+func NewAuditService(events *store.AuditStore) *AuditService {
+	return &AuditService{Events: events}
+}
+
+// Record writes an audit event for an action that already happened. It never
+// fails the caller: a lost audit entry is logged rather than undoing the
+// action. A nil AuditService records nothing.
+func (s *AuditService) Record(ctx context.Context, actorID string, e AuditEntry) {
+	if s == nil || s.Events == nil {
+		return
+	}
+
+	var targetID *string
+	if e.TargetID != "" {
+		targetID = &e.TargetID
+	}
+	var actor, ip *string
+	if actorID != "" {
+		actor = &actorID
+	}
+	if v := clientIP(ctx); v != "" {
+		ip = &v
+	}
+
+	err := s.Events.Insert(ctx, store.AuditEvent{
+		ActorID:    actor,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   targetID,
+		Before:     e.Before,
+		After:      e.After,
+		IP:         ip,
+	})
+	if err != nil {
+		log.Println("audit:", e.Action, err)
+	}
+}
+
+func (s *AuditService) ListEvents(ctx context.Context, currentUser *store.User, params store.ListAuditParams) ([]store.AuditEvent, error) {
+	if s == nil || s.Events == nil {
+		return nil, errors.New("audit service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return nil, ErrUnauthenticated
+	}
+	if !can(currentUser, authz.AuditRead) {
+		return nil, ErrForbidden
+	}
+
+	params.ActorID = strings.TrimSpace(params.ActorID)
+	if params.ActorID != "" && !isUUID(params.ActorID) {
+		return nil, ErrInvalidInput
+	}
+	params.Action = strings.TrimSpace(params.Action)
+	params.TargetType = strings.TrimSpace(params.TargetType)
+	params.TargetID = strings.TrimSpace(params.TargetID)
+	return s.Events.List(ctx, params)
+}
+
+// isUUID reports whether s is a UUID in its canonical hyphenated form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
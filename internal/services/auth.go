@@ -0,0 +1,466 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"skyhow/internal/auth"
+	"skyhow/internal/store"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/oauth2"
+)
+
+// oauthStateTTL is how long a user has to finish logging in at the provider.
+const oauthStateTTL = 10 * time.Minute
+
+type AuthService struct {
+	Providers   map[string]auth.Provider
+	Users       *store.UserStore
+	Sessions    *store.SessionStore
+	OAuthStates *store.OAuthStateStore
+	Audit       *AuditService
+	SessionTTL  time.Duration
+	// SessionMaxLifetime caps how long sliding renewal can keep a session
+	// alive after login.
+	SessionMaxLifetime time.Duration
+}
+
+func NewAuthService(
+	providers []auth.Provider,
+	users *store.UserStore,
+	sessions *store.SessionStore,
+	oauthStates *store.OAuthStateStore,
+	audit *AuditService,
+	sessionTTL time.Duration,
+	sessionMaxLifetime time.Duration,
+) *AuthService {
+	if sessionTTL <= 0 {
+		sessionTTL = 14 * 24 * time.Hour
+	}
+	if sessionMaxLifetime < sessionTTL {
+		sessionMaxLifetime = 90 * 24 * time.Hour
+		if sessionMaxLifetime < sessionTTL {
+			sessionMaxLifetime = sessionTTL
+		}
+	}
+
+	byName := make(map[string]auth.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+
+	return &AuthService{
+		Providers:          byName,
+		Users:              users,
+		Sessions:           sessions,
+		OAuthStates:        oauthStates,
+		Audit:              audit,
+		SessionTTL:         sessionTTL,
+		SessionMaxLifetime: sessionMaxLifetime,
+	}
+}
+
+func (s *AuthService) Provider(name string) (auth.Provider, bool) {
+	p, ok := s.Providers[name]
+	return p, ok
+}
+
+// BeginLogin records a new login attempt and returns the provider URL to
+// send the browser to, plus the state to bind to the browser. The PKCE
+// verifier, nonce and return-to path stay server-side, keyed by the state.
+// linkUser is set when the flow should link an identity instead of logging in.
+func (s *AuthService) BeginLogin(ctx context.Context, providerName, returnTo string, linkUser *store.User) (string, string, error) {
+	if s.OAuthStates == nil {
+		return "", "", errors.New("auth service not configured")
+	}
+
+	provider, ok := s.Provider(providerName)
+	if !ok {
+		return "", "", ErrNotFound
+	}
+
+	st := store.OAuthState{
+		Provider:     provider.Name(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		ReturnTo:     auth.SafeReturnTo(returnTo),
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if linkUser != nil {
+		if !isAuthedActive(linkUser) {
+			return "", "", ErrUnauthenticated
+		}
+		st.LinkUserID = &linkUser.ID
+	}
+
+	state, err := auth.RandomState()
+	if err != nil {
+		return "", "", err
+	}
+	if st.Nonce, err = auth.RandomState(); err != nil {
+		return "", "", err
+	}
+
+	if err := s.OAuthStates.Create(ctx, state, st); err != nil {
+		return "", "", err
+	}
+
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(st.CodeVerifier)}
+	if _, ok := provider.(auth.NonceVerifier); ok {
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", st.Nonce))
+	}
+	return provider.AuthURL(state, opts...), state, nil
+}
+
+// ConsumeState looks up and invalidates the login attempt behind state. A
+// state can only be consumed once, so a replayed callback fails here.
+func (s *AuthService) ConsumeState(ctx context.Context, providerName, state string) (store.OAuthState, error) {
+	if s.OAuthStates == nil {
+		return store.OAuthState{}, errors.New("auth service not configured")
+	}
+	if state == "" {
+		return store.OAuthState{}, ErrInvalidInput
+	}
+
+	st, err := s.OAuthStates.Consume(ctx, state)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return store.OAuthState{}, ErrInvalidInput
+		}
+		return store.OAuthState{}, err
+	}
+	if st.Provider != providerName {
+		return store.OAuthState{}, ErrInvalidInput
+	}
+	return st, nil
+}
+
+func (s *AuthService) Login(ctx context.Context, st store.OAuthState, code string, meta store.SessionMeta) (string, time.Time, error) {
+	if code == "" {
+		return "", time.Time{}, errors.New("missing oauth code")
+	}
+	if s.Users == nil || s.Sessions == nil {
+		return "", time.Time{}, errors.New("auth service not configured")
+	}
+
+	profile, err := s.fetchProfile(ctx, st, code)
+	if err != nil {
+		if err == ErrForbidden {
+			s.Audit.Record(ctx, "", AuditEntry{
+				Action:     "auth.login_denied",
+				TargetType: "provider",
+				TargetID:   st.Provider,
+			})
+		}
+		return "", time.Time{}, err
+	}
+
+	resolved, err := s.Users.ResolveLogin(ctx, profile)
+	if err != nil {
+		return "", time.Time{}, errors.New("failed to resolve user")
+	}
+	userID := resolved.UserID
+	if resolved.PreviousRole != "" {
+		s.Audit.Record(ctx, "", AuditEntry{
+			Action:     "user.change_role",
+			TargetType: "user",
+			TargetID:   userID,
+			Before:     map[string]any{"role": resolved.PreviousRole},
+			After:      map[string]any{"role": profile.Role, "source": profile.Provider},
+		})
+	}
+	if !resolved.IsActive {
+		s.Audit.Record(ctx, "", AuditEntry{
+			Action:     "auth.login_denied",
+			TargetType: "user",
+			TargetID:   userID,
+			After:      map[string]any{"provider": profile.Provider, "reason": "inactive"},
+		})
+		return "", time.Time{}, ErrForbidden
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.SessionTTL)
+	sessionID, err := s.Sessions.Create(ctx, userID, expiresAt, now.Add(s.SessionMaxLifetime), meta)
+	if err != nil {
+		return "", time.Time{}, errors.New("failed to create session")
+	}
+
+	after := map[string]any{"provider": profile.Provider, "user_agent": meta.UserAgent}
+	if profile.Role != "" {
+		after["role"] = profile.Role
+	}
+	s.Audit.Record(ctx, userID, AuditEntry{
+		Action:     "auth.login",
+		TargetType: "user",
+		TargetID:   userID,
+		After:      after,
+	})
+	return sessionID, expiresAt, nil
+}
+
+// Link attaches the provider account behind code to currentUser, who must be
+// the user that started the link flow.
+func (s *AuthService) Link(ctx context.Context, currentUser *store.User, st store.OAuthState, code string) error {
+	if code == "" {
+		return ErrInvalidInput
+	}
+	if s.Users == nil {
+		return errors.New("auth service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return ErrUnauthenticated
+	}
+	if st.LinkUserID == nil || *st.LinkUserID != currentUser.ID {
+		return ErrForbidden
+	}
+
+	profile, err := s.fetchProfile(ctx, st, code)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Users.LinkIdentity(ctx, currentUser.ID, profile); err != nil {
+		if err == store.ErrIdentityConflict {
+			return ErrConflict
+		}
+		return err
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "identity.link",
+		TargetType: "user",
+		TargetID:   currentUser.ID,
+		After:      map[string]any{"provider": profile.Provider, "provider_user_id": profile.ProviderUserID},
+	})
+	return nil
+}
+
+func (s *AuthService) fetchProfile(ctx context.Context, st store.OAuthState, code string) (store.IdentityProfile, error) {
+	provider, ok := s.Provider(st.Provider)
+	if !ok {
+		return store.IdentityProfile{}, ErrNotFound
+	}
+
+	token, err := provider.Exchange(ctx, code, oauth2.VerifierOption(st.CodeVerifier))
+	if err != nil {
+		return store.IdentityProfile{}, errors.New("token exchange failed")
+	}
+
+	var subject string
+	if nv, ok := provider.(auth.NonceVerifier); ok {
+		subject, err = nv.VerifyNonce(token, st.Nonce)
+		if err != nil {
+			return store.IdentityProfile{}, err
+		}
+	}
+
+	p, err := provider.FetchProfile(ctx, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrNotGuildMember) {
+			return store.IdentityProfile{}, ErrForbidden
+		}
+		return store.IdentityProfile{}, errors.New("failed to fetch " + provider.Name() + " user")
+	}
+	// OIDC Core 5.3.2: the userinfo response must be about the user the ID
+	// token was issued for.
+	if subject != "" && p.ProviderUserID != subject {
+		return store.IdentityProfile{}, errors.New(provider.Name() + " userinfo subject mismatch")
+	}
+
+	return store.IdentityProfile{
+		Provider:       provider.Name(),
+		ProviderUserID: p.ProviderUserID,
+		Email:          p.Email,
+		EmailVerified:  p.EmailVerified,
+		DisplayName:    p.DisplayName,
+		AvatarURL:      p.AvatarURL,
+		Role:           p.Role,
+	}, nil
+}
+
+func (s *AuthService) ListIdentities(ctx context.Context, currentUser *store.User) ([]store.Identity, error) {
+	if s.Users == nil {
+		return nil, errors.New("auth service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return nil, ErrUnauthenticated
+	}
+	return s.Users.ListIdentities(ctx, currentUser.ID)
+}
+
+// UnlinkIdentity removes a login method from the current user. The last one
+// can't be removed, or the account would become unreachable.
+func (s *AuthService) UnlinkIdentity(ctx context.Context, currentUser *store.User, identityID string) error {
+	if s.Users == nil {
+		return errors.New("auth service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return ErrUnauthenticated
+	}
+	if strings.TrimSpace(identityID) == "" {
+		return ErrInvalidInput
+	}
+
+	switch err := s.Users.UnlinkIdentity(ctx, currentUser.ID, identityID); err {
+	case nil:
+		s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+			Action:     "identity.unlink",
+			TargetType: "identity",
+			TargetID:   identityID,
+		})
+		return nil
+	case pgx.ErrNoRows:
+		return ErrNotFound
+	case store.ErrLastIdentity:
+		return ErrConflict
+	default:
+		return err
+	}
+}
+
+func (s *AuthService) Logout(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	if s.Sessions == nil {
+		return errors.New("auth service not configured")
+	}
+	userID, err := s.Sessions.Delete(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if userID != "" {
+		s.Audit.Record(ctx, userID, AuditEntry{
+			Action:     "auth.logout",
+			TargetType: "user",
+			TargetID:   userID,
+		})
+	}
+	return nil
+}
+
+func (s *AuthService) ListSessions(ctx context.Context, currentUser *store.User) ([]store.Session, error) {
+	if s.Sessions == nil {
+		return nil, errors.New("auth service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return nil, ErrUnauthenticated
+	}
+	return s.Sessions.ListByUser(ctx, currentUser.ID)
+}
+
+func (s *AuthService) RevokeSession(ctx context.Context, currentUser *store.User, publicID string) error {
+	if s.Sessions == nil {
+		return errors.New("auth service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return ErrUnauthenticated
+	}
+	if strings.TrimSpace(publicID) == "" {
+		return ErrInvalidInput
+	}
+
+	if err := s.Sessions.DeleteByPublicID(ctx, currentUser.ID, publicID); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "session.revoke",
+		TargetType: "session",
+		TargetID:   publicID,
+	})
+	return nil
+}
+
+// RevokeOtherSessions logs the user out everywhere except the session making
+// the request.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, currentUser *store.User, currentSessionID string) (int64, error) {
+	if s.Sessions == nil {
+		return 0, errors.New("auth service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return 0, ErrUnauthenticated
+	}
+	if currentSessionID == "" {
+		return 0, ErrInvalidInput
+	}
+	n, err := s.Sessions.DeleteOthers(ctx, currentUser.ID, currentSessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "session.revoke_others",
+		TargetType: "user",
+		TargetID:   currentUser.ID,
+		After:      map[string]any{"revoked": n},
+	})
+	return n, nil
+}
+
+// RunExpiredSessionSweeper deletes expired sessions in batches every interval
+// until ctx is cancelled. Abandoned OAuth login attempts are cleared too.
+func (s *AuthService) RunExpiredSessionSweeper(ctx context.Context, interval time.Duration, batchSize int) {
+	if s.Sessions == nil || interval <= 0 {
+		return
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var total int64
+			for {
+				n, err := s.Sessions.DeleteExpired(ctx, batchSize)
+				if err != nil {
+					log.Println("session sweeper:", err)
+					break
+				}
+				total += n
+				if n < int64(batchSize) {
+					break
+				}
+			}
+			if total > 0 {
+				log.Println("session sweeper: deleted", total, "expired sessions")
+			}
+			s.sweepOAuthStates(ctx, batchSize)
+		}
+	}
+}
+
+func (s *AuthService) sweepOAuthStates(ctx context.Context, batchSize int) {
+	if s.OAuthStates == nil {
+		return
+	}
+
+	var total int64
+	for {
+		n, err := s.OAuthStates.DeleteExpired(ctx, batchSize)
+		if err != nil {
+			log.Println("session sweeper:", err)
+			break
+		}
+		total += n
+		if n < int64(batchSize) {
+			break
+		}
+	}
+	if total > 0 {
+		log.Println("session sweeper: deleted", total, "expired oauth states")
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"skyhow/internal/auth"
+	"skyhow/internal/store"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeOIDCProvider returns an ID token for idSubject and a userinfo profile
+// for infoSubject.
+type fakeOIDCProvider struct {
+	idSubject   string
+	infoSubject string
+}
+
+func (p fakeOIDCProvider) Name() string { return "oidc" }
+
+func (p fakeOIDCProvider) AuthURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return "https://idp.example/authorize?state=" + state
+}
+
+func (p fakeOIDCProvider) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: "access-token"}, nil
+}
+
+func (p fakeOIDCProvider) FetchProfile(ctx context.Context, token *oauth2.Token) (auth.Profile, error) {
+	return auth.Profile{ProviderUserID: p.infoSubject, DisplayName: "player"}, nil
+}
+
+func (p fakeOIDCProvider) VerifyNonce(token *oauth2.Token, nonce string) (string, error) {
+	return p.idSubject, nil
+}
+
+func TestFetchProfileChecksSubject(t *testing.T) {
+	tests := []struct {
+		name        string
+		infoSubject string
+		wantErr     bool
+	}{
+		{name: "same subject", infoSubject: "user-123"},
+		{name: "different subject", infoSubject: "user-456", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAuthService([]auth.Provider{fakeOIDCProvider{idSubject: "user-123", infoSubject: tt.infoSubject}}, nil, nil, nil, nil, 0, 0)
+
+			p, err := s.fetchProfile(context.Background(), store.OAuthState{Provider: "oidc", Nonce: "n-1"}, "code")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "subject mismatch") {
+					t.Fatalf("got %v, want subject mismatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchProfile: %v", err)
+			}
+			if p.ProviderUserID != "user-123" {
+				t.Fatalf("provider user id = %q", p.ProviderUserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"skyhow/internal/authz"
+	"skyhow/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxGameVersionLength    = 32
+	maxOutdatedReasonLength = 500
+)
+
+// MarkGuideOutdated flags a guide as no longer matching the current game,
+// with a reason shown to readers. Marking an already flagged guide replaces
+// the reason.
+func (s *GuideService) MarkGuideOutdated(ctx context.Context, currentUser *store.User, guideID, reason string) error {
+	g, err := s.authorizeOutdated(ctx, currentUser, guideID)
+	if err != nil {
+		return err
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > maxOutdatedReasonLength {
+		return ErrInvalidInput
+	}
+
+	if err := s.Guides.MarkOutdated(ctx, guideID, currentUser.ID, reason); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	var before map[string]any
+	if g.Outdated != nil {
+		before = map[string]any{"reason": g.Outdated.Reason}
+	}
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "guide.mark_outdated",
+		TargetType: "guide",
+		TargetID:   guideID,
+		Before:     before,
+		After:      map[string]any{"reason": reason},
+	})
+	return nil
+}
+
+// ClearGuideOutdated removes the outdated flag, typically after the guide
+// has been brought up to date.
+func (s *GuideService) ClearGuideOutdated(ctx context.Context, currentUser *store.User, guideID string) error {
+	g, err := s.authorizeOutdated(ctx, currentUser, guideID)
+	if err != nil {
+		return err
+	}
+	if g.Outdated == nil {
+		return nil
+	}
+
+	if err := s.Guides.ClearOutdated(ctx, guideID); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "guide.clear_outdated",
+		TargetType: "guide",
+		TargetID:   guideID,
+		Before:     map[string]any{"reason": g.Outdated.Reason},
+	})
+	return nil
+}
+
+func (s *GuideService) authorizeOutdated(ctx context.Context, currentUser *store.User, guideID string) (store.Guide, error) {
+	if s.Guides == nil {
+		return store.Guide{}, errors.New("guide service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return store.Guide{}, ErrUnauthenticated
+	}
+	if !can(currentUser, authz.GuidesMarkOutdated) {
+		return store.Guide{}, ErrForbidden
+	}
+	if strings.TrimSpace(guideID) == "" {
+		return store.Guide{}, ErrInvalidInput
+	}
+
+	g, err := s.Guides.GetGuideByID(ctx, guideID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return store.Guide{}, ErrNotFound
+		}
+		return store.Guide{}, err
+	}
+	return g, nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"skyhow/internal/authz"
+	"skyhow/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubmitGuide sends the author's draft (or rejected) guide to the review
+// queue.
+func (s *GuideService) SubmitGuide(ctx context.Context, currentUser *store.User, guideID string, expectedVersion int) (int, error) {
+	if s.Guides == nil {
+		return 0, errors.New("guide service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return 0, ErrUnauthenticated
+	}
+	if strings.TrimSpace(guideID) == "" {
+		return 0, ErrInvalidInput
+	}
+
+	g, err := s.Guides.GetGuideByID(ctx, guideID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	if g.CreatorID != currentUser.ID || !can(currentUser, authz.GuidesSubmit) {
+		return 0, ErrForbidden
+	}
+	if g.Version == expectedVersion && g.Status != "draft" && g.Status != "rejected" {
+		return 0, ErrConflict
+	}
+
+	version, err := s.Guides.ChangeStatus(ctx, guideID, currentUser.ID, "pending_review", expectedVersion)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "guide.submit",
+		TargetType: "guide",
+		TargetID:   guideID,
+		Before:     map[string]any{"status": g.Status, "version": g.Version},
+		After:      map[string]any{"status": "pending_review", "version": version},
+	})
+	return version, nil
+}
+
+// ApproveGuide publishes a guide that is pending review.
+func (s *GuideService) ApproveGuide(ctx context.Context, currentUser *store.User, guideID, comment string, expectedVersion int) (int, error) {
+	return s.reviewGuide(ctx, currentUser, guideID, true, comment, expectedVersion)
+}
+
+// RequestChanges sends a pending guide back to its author with a comment.
+func (s *GuideService) RequestChanges(ctx context.Context, currentUser *store.User, guideID, comment string, expectedVersion int) (int, error) {
+	if strings.TrimSpace(comment) == "" {
+		return 0, ErrInvalidInput
+	}
+	return s.reviewGuide(ctx, currentUser, guideID, false, comment, expectedVersion)
+}
+
+func (s *GuideService) reviewGuide(ctx context.Context, currentUser *store.User, guideID string, approve bool, comment string, expectedVersion int) (int, error) {
+	if s.Guides == nil {
+		return 0, errors.New("guide service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return 0, ErrUnauthenticated
+	}
+	if !can(currentUser, authz.GuidesReview) {
+		return 0, ErrForbidden
+	}
+	if strings.TrimSpace(guideID) == "" {
+		return 0, ErrInvalidInput
+	}
+
+	g, err := s.Guides.GetGuideByID(ctx, guideID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	if g.Version == expectedVersion && g.Status != "pending_review" {
+		return 0, ErrConflict
+	}
+
+	version, err := s.Guides.ReviewGuide(ctx, guideID, currentUser.ID, approve, optionalString(comment), expectedVersion)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	action, status := "guide.request_changes", "rejected"
+	if approve {
+		action, status = "guide.approve", "published"
+	}
+	after := map[string]any{"status": status, "version": version}
+	if c := strings.TrimSpace(comment); c != "" {
+		after["comment"] = c
+	}
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     action,
+		TargetType: "guide",
+		TargetID:   guideID,
+		Before:     map[string]any{"status": g.Status, "version": g.Version},
+		After:      after,
+	})
+	return version, nil
+}
+
+// ListGuideReviews returns the review history of a guide to its author and
+// editors.
+func (s *GuideService) ListGuideReviews(ctx context.Context, currentUser *store.User, guideID string) ([]store.GuideReview, error) {
+	if err := s.authorizeGuideEditor(ctx, currentUser, guideID); err != nil {
+		return nil, err
+	}
+	return s.Guides.ListReviews(ctx, guideID)
+}
+
+func (s *GuideService) ListReviewQueue(ctx context.Context, currentUser *store.User, limit, offset int) ([]store.Guide, error) {
+	if s.Guides == nil {
+		return nil, errors.New("guide service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return nil, ErrUnauthenticated
+	}
+	if !can(currentUser, authz.GuidesReview) {
+		return nil, ErrForbidden
+	}
+	return s.Guides.ListPendingReview(ctx, limit, offset)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"skyhow/internal/diff"
+	"skyhow/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type RevisionDiff struct {
+	From  store.GuideRevision
+	To    store.GuideRevision
+	Lines []diff.Line
+}
+
+func (s *GuideService) ListGuideRevisions(ctx context.Context, currentUser *store.User, guideID string) ([]store.GuideRevision, error) {
+	if err := s.authorizeGuideEditor(ctx, currentUser, guideID); err != nil {
+		return nil, err
+	}
+	return s.Guides.ListRevisions(ctx, guideID)
+}
+
+func (s *GuideService) GetGuideRevision(ctx context.Context, currentUser *store.User, guideID string, revision int) (store.GuideRevision, error) {
+	if err := s.authorizeGuideEditor(ctx, currentUser, guideID); err != nil {
+		return store.GuideRevision{}, err
+	}
+	if revision <= 0 {
+		return store.GuideRevision{}, ErrInvalidInput
+	}
+
+	r, err := s.Guides.GetRevision(ctx, guideID, revision)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return store.GuideRevision{}, ErrNotFound
+		}
+		return store.GuideRevision{}, err
+	}
+	return r, nil
+}
+
+// DiffGuideRevisions diffs the content of revision from against revision to.
+// A from of 0 diffs against an empty document.
+func (s *GuideService) DiffGuideRevisions(ctx context.Context, currentUser *store.User, guideID string, from, to int) (RevisionDiff, error) {
+	if err := s.authorizeGuideEditor(ctx, currentUser, guideID); err != nil {
+		return RevisionDiff{}, err
+	}
+	if from < 0 || to <= 0 {
+		return RevisionDiff{}, ErrInvalidInput
+	}
+
+	var out RevisionDiff
+	var err error
+
+	out.To, err = s.Guides.GetRevision(ctx, guideID, to)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return RevisionDiff{}, ErrNotFound
+		}
+		return RevisionDiff{}, err
+	}
+
+	if from > 0 {
+		out.From, err = s.Guides.GetRevision(ctx, guideID, from)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return RevisionDiff{}, ErrNotFound
+			}
+			return RevisionDiff{}, err
+		}
+	}
+
+	out.Lines, err = diff.Lines(out.From.Content, out.To.Content)
+	if err != nil {
+		if err == diff.ErrTooLarge {
+			return RevisionDiff{}, ErrInvalidInput
+		}
+		return RevisionDiff{}, err
+	}
+	return out, nil
+}
+
+// RestoreGuideRevision writes an old revision back as the current version.
+// It goes through UpdateGuide, so the permission checks are identical and the
+// restore itself shows up as a new revision.
+func (s *GuideService) RestoreGuideRevision(ctx context.Context, currentUser *store.User, guideID string, revision, expectedVersion int) (int, error) {
+	r, err := s.GetGuideRevision(ctx, currentUser, guideID, revision)
+	if err != nil {
+		return 0, err
+	}
+
+	tags := r.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return s.UpdateGuide(ctx, currentUser, guideID, r.Title, r.Content, &tags, nil, ScheduleChange{}, expectedVersion)
+}
+
+func (s *GuideService) authorizeGuideEditor(ctx context.Context, currentUser *store.User, guideID string) error {
+	if s.Guides == nil {
+		return errors.New("guide service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return ErrUnauthenticated
+	}
+	if strings.TrimSpace(guideID) == "" {
+		return ErrInvalidInput
+	}
+
+	g, err := s.Guides.GetGuideByID(ctx, guideID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if !canEditGuide(currentUser, g.CreatorID) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"skyhow/internal/authz"
+	"skyhow/internal/store"
+)
+
+// ScheduleChange is a partial update of a guide's schedule. Fields whose Set
+// flag is false are left alone; a set field with a nil time clears it.
+type ScheduleChange struct {
+	PublishAt    *time.Time
+	SetPublishAt bool
+	ArchiveAt    *time.Time
+	SetArchiveAt bool
+}
+
+func (c ScheduleChange) apply(s store.GuideSchedule) store.GuideSchedule {
+	if c.SetPublishAt {
+		s.PublishAt = c.PublishAt
+	}
+	if c.SetArchiveAt {
+		s.ArchiveAt = c.ArchiveAt
+	}
+	return s
+}
+
+// checkSchedule validates a new schedule against the current one (nil for new
+// guides). Scheduling a publish skips review, so it needs guides:publish;
+// clearing a schedule or setting an archive time only needs edit rights.
+func checkSchedule(u *store.User, current *store.GuideSchedule, next store.GuideSchedule) error {
+	if next.PublishAt != nil {
+		unchanged := current != nil && current.PublishAt != nil && current.PublishAt.Equal(*next.PublishAt)
+		if !unchanged && !can(u, authz.GuidesPublish) {
+			return ErrForbidden
+		}
+	}
+	if next.PublishAt != nil && next.ArchiveAt != nil && !next.ArchiveAt.After(*next.PublishAt) {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+func addScheduleSummary(m map[string]any, s store.GuideSchedule) {
+	if s.PublishAt != nil {
+		m["publish_at"] = s.PublishAt.UTC().Format(time.RFC3339)
+	}
+	if s.ArchiveAt != nil {
+		m["archive_at"] = s.ArchiveAt.UTC().Format(time.RFC3339)
+	}
+}
+
+// RunScheduler publishes and archives guides whose scheduled time has passed,
+// every interval until ctx is cancelled. It is safe to run on every replica:
+// each pass takes a Postgres advisory lock and replicas that miss it skip the
+// pass.
+func (s *GuideService) RunScheduler(ctx context.Context, interval time.Duration, batchSize int) {
+	if s.Guides == nil || interval <= 0 {
+		return
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runScheduledTransitions(ctx, "publish", "published", batchSize, s.Guides.PublishDue)
+			s.runScheduledTransitions(ctx, "archive", "archived", batchSize, s.Guides.ArchiveDue)
+		}
+	}
+}
+
+// runScheduledTransitions drains one kind of due transition in batches and
+// audits every guide it moved.
+func (s *GuideService) runScheduledTransitions(ctx context.Context, name, status string, batchSize int, due func(context.Context, int) ([]string, error)) {
+	for {
+		ids, err := due(ctx, batchSize)
+		if err != nil {
+			log.Println("guide scheduler:", name, err)
+			return
+		}
+		for _, id := range ids {
+			s.Audit.Record(ctx, "", AuditEntry{
+				Action:     "guide.scheduled_" + name,
+				TargetType: "guide",
+				TargetID:   id,
+				After:      map[string]any{"status": status},
+			})
+		}
+		if len(ids) > 0 {
+			log.Println("guide scheduler:", name, len(ids), "guides")
+		}
+		if len(ids) < batchSize {
+			return
+		}
+	}
+}
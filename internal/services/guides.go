@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
+	"skyhow/internal/authz"
 	"skyhow/internal/store"
 
 	"github.com/jackc/pgx/v5"
@@ -15,23 +17,28 @@ var (
 	ErrForbidden       = errors.New("forbidden")
 	ErrNotFound        = errors.New("not found")
 	ErrInvalidInput    = errors.New("invalid input")
+	ErrConflict        = errors.New("conflict")
 )
 
 type GuideService struct {
 	Guides *store.GuideStore
+	Audit  *AuditService
 }
 
-func NewGuideService(guides *store.GuideStore) *GuideService {
-	return &GuideService{Guides: guides}
+func NewGuideService(guides *store.GuideStore, audit *AuditService) *GuideService {
+	return &GuideService{Guides: guides, Audit: audit}
 }
 
-func (s *GuideService) CreateGuide(ctx context.Context, currentUser *store.User, title, content string, tags []string) (string, error) {
+func (s *GuideService) CreateGuide(ctx context.Context, currentUser *store.User, title, content, gameVersion string, tags []string, schedule store.GuideSchedule) (string, error) {
 	if s.Guides == nil {
 		return "", errors.New("guide service not configured")
 	}
 	if !isAuthedActive(currentUser) {
 		return "", ErrUnauthenticated
 	}
+	if !can(currentUser, authz.GuidesCreate) {
+		return "", ErrForbidden
+	}
 
 	title = strings.TrimSpace(title)
 	if title == "" {
@@ -40,121 +47,226 @@ func (s *GuideService) CreateGuide(ctx context.Context, currentUser *store.User,
 	if content == "" {
 		return "", ErrInvalidInput
 	}
+	gameVersion = strings.TrimSpace(gameVersion)
+	if len(gameVersion) > maxGameVersionLength {
+		return "", ErrInvalidInput
+	}
+	if err := checkSchedule(currentUser, nil, schedule); err != nil {
+		return "", err
+	}
 
-	guideID, err := s.Guides.CreateGuide(ctx, currentUser.ID, title, content, tags)
+	guideID, err := s.Guides.CreateGuide(ctx, currentUser.ID, title, content, gameVersion, tags, schedule)
 	if err != nil {
 		return "", err
 	}
+
+	after := map[string]any{"title": title, "status": "draft", "tags": tags}
+	if gameVersion != "" {
+		after["game_version"] = gameVersion
+	}
+	addScheduleSummary(after, schedule)
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "guide.create",
+		TargetType: "guide",
+		TargetID:   guideID,
+		After:      after,
+	})
 	return guideID, nil
 }
 
-func (s *GuideService) UpdateGuide(ctx context.Context, currentUser *store.User, guideID, title, content string, tags *[]string) error {
+// UpdateGuide replaces a guide's title and content. tags and gameVersion are
+// optional (an empty gameVersion clears it), and only the schedule fields
+// marked in change are touched.
+// Owners without guides:publish who edit a published or archived guide move
+// it to pending_review (or draft if they can't submit either), and their
+// content edits cancel a scheduled publish.
+func (s *GuideService) UpdateGuide(ctx context.Context, currentUser *store.User, guideID, title, content string, tags *[]string, gameVersion *string, change ScheduleChange, expectedVersion int) (int, error) {
 	if s.Guides == nil {
-		return errors.New("guide service not configured")
+		return 0, errors.New("guide service not configured")
 	}
 	if !isAuthedActive(currentUser) {
-		return ErrUnauthenticated
+		return 0, ErrUnauthenticated
 	}
 	if strings.TrimSpace(guideID) == "" {
-		return ErrInvalidInput
+		return 0, ErrInvalidInput
 	}
 
 	g, err := s.Guides.GetGuideByID(ctx, guideID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return ErrNotFound
+			return 0, ErrNotFound
 		}
-		return err
+		return 0, err
 	}
 
 	if !canEditGuide(currentUser, g.CreatorID) {
-		return ErrForbidden
+		return 0, ErrForbidden
 	}
 
 	title = strings.TrimSpace(title)
 	if title == "" || content == "" {
-		return ErrInvalidInput
+		return 0, ErrInvalidInput
+	}
+	if gameVersion != nil && len(strings.TrimSpace(*gameVersion)) > maxGameVersionLength {
+		return 0, ErrInvalidInput
 	}
 
-	if currentUser.Role != "editor" && currentUser.Role != "admin" {
-		if err := s.Guides.UpdateGuide(ctx, guideID, currentUser.ID, title, content); err != nil {
-			if err == pgx.ErrNoRows {
-				return ErrNotFound
-			}
-			return err
+	current := store.GuideSchedule{PublishAt: g.PublishAt, ArchiveAt: g.ArchiveAt}
+	var schedule *store.GuideSchedule
+	if change.SetPublishAt || change.SetArchiveAt {
+		next := change.apply(current)
+		if err := checkSchedule(currentUser, &current, next); err != nil {
+			return 0, err
 		}
-	} else {
-		if currentUser.ID != g.CreatorID {
-			return errors.New("editor/admin updates require a store method (UpdateGuideAsEditor)")
+		schedule = &next
+	}
+
+	// A scheduled publish was signed off for the text as it stood, so a
+	// content change by someone who can't publish cancels it rather than
+	// letting the scheduler publish unreviewed text.
+	clearedPublishAt := false
+	if (title != g.Title || content != g.Content) && !can(currentUser, authz.GuidesPublish) {
+		next := current
+		if schedule != nil {
+			next = *schedule
 		}
-		if err := s.Guides.UpdateGuide(ctx, guideID, currentUser.ID, title, content); err != nil {
-			if err == pgx.ErrNoRows {
-				return ErrNotFound
-			}
-			return err
+		if next.PublishAt != nil {
+			next.PublishAt = nil
+			schedule = &next
+			clearedPublishAt = true
 		}
 	}
 
-	if tags != nil {
-		if currentUser.ID != g.CreatorID {
-			return errors.New("editor/admin tag changes require a store method (ReplaceTagsAsEditor)")
+	opt := store.GuideUpdate{Tags: tags, Schedule: schedule, GameVersion: gameVersion}
+
+	// Edits to a live guide go live immediately, so authors who can't publish
+	// send it back through review instead.
+	if (g.Status == "published" || g.Status == "archived") && !can(currentUser, authz.GuidesPublish) {
+		status := "draft"
+		if can(currentUser, authz.GuidesSubmit) {
+			status = "pending_review"
 		}
-		if err := s.Guides.ReplaceTags(ctx, guideID, currentUser.ID, *tags); err != nil {
-			if err == pgx.ErrNoRows {
-				return ErrNotFound
-			}
-			return err
+		opt.Status = &status
+	}
+
+	var version int
+	if currentUser.ID == g.CreatorID {
+		version, err = s.Guides.UpdateGuide(ctx, guideID, currentUser.ID, title, content, opt, expectedVersion)
+	} else {
+		version, err = s.Guides.UpdateGuideAsEditor(ctx, guideID, currentUser.ID, title, content, opt, expectedVersion)
+	}
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrNotFound
 		}
+		return 0, err
 	}
 
-	return nil
+	after := map[string]any{"title": title, "version": version, "content_length": len(content)}
+	if tags != nil {
+		after["tags"] = *tags
+	}
+	if schedule != nil {
+		addScheduleSummary(after, *schedule)
+	}
+	if gameVersion != nil {
+		after["game_version"] = strings.TrimSpace(*gameVersion)
+	}
+	before := map[string]any{"title": g.Title, "version": g.Version, "content_length": len(g.Content)}
+	if opt.Status != nil {
+		before["status"] = g.Status
+		after["status"] = *opt.Status
+	}
+	if clearedPublishAt {
+		if g.PublishAt != nil {
+			before["publish_at"] = g.PublishAt.UTC().Format(time.RFC3339)
+		}
+		after["publish_at"] = nil
+	}
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "guide.update",
+		TargetType: "guide",
+		TargetID:   guideID,
+		Before:     before,
+		After:      after,
+	})
+	return version, nil
+}
+
+func (s *GuideService) PublishGuide(ctx context.Context, currentUser *store.User, guideID string, expectedVersion int) (int, error) {
+	return s.setStatus(ctx, currentUser, guideID, "published", expectedVersion)
 }
 
-func (s *GuideService) PublishGuide(ctx context.Context, currentUser *store.User, guideID string) error {
-	return s.setStatus(ctx, currentUser, guideID, "published")
+func (s *GuideService) UnpublishGuide(ctx context.Context, currentUser *store.User, guideID string, expectedVersion int) (int, error) {
+	return s.setStatus(ctx, currentUser, guideID, "draft", expectedVersion)
 }
 
-func (s *GuideService) UnpublishGuide(ctx context.Context, currentUser *store.User, guideID string) error {
-	return s.setStatus(ctx, currentUser, guideID, "draft")
+// ArchiveGuide takes a published guide out of listings while keeping it
+// readable at its URL. Publishing it again brings it back.
+func (s *GuideService) ArchiveGuide(ctx context.Context, currentUser *store.User, guideID string, expectedVersion int) (int, error) {
+	return s.setStatus(ctx, currentUser, guideID, "archived", expectedVersion)
 }
 
-func (s *GuideService) setStatus(ctx context.Context, currentUser *store.User, guideID, status string) error {
+func (s *GuideService) setStatus(ctx context.Context, currentUser *store.User, guideID, status string, expectedVersion int) (int, error) {
 	if s.Guides == nil {
-		return errors.New("guide service not configured")
+		return 0, errors.New("guide service not configured")
 	}
 	if !isAuthedActive(currentUser) {
-		return ErrUnauthenticated
+		return 0, ErrUnauthenticated
 	}
 	if strings.TrimSpace(guideID) == "" {
-		return ErrInvalidInput
+		return 0, ErrInvalidInput
 	}
 
 	g, err := s.Guides.GetGuideByID(ctx, guideID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return ErrNotFound
+			return 0, ErrNotFound
 		}
-		return err
+		return 0, err
 	}
 
 	if !canEditGuide(currentUser, g.CreatorID) {
-		return ErrForbidden
+		return 0, ErrForbidden
 	}
-
-	if currentUser.ID != g.CreatorID {
-		return errors.New("editor/admin status changes require a store method (ChangeStatusAsEditor)")
+	if !canSetStatus(currentUser, status) {
+		return 0, ErrForbidden
+	}
+	if g.Version == expectedVersion && !statusTransitionAllowed(g.Status, status) {
+		return 0, ErrConflict
 	}
 
-	if err := s.Guides.ChangeStatus(ctx, guideID, currentUser.ID, status); err != nil {
+	var version int
+	if currentUser.ID == g.CreatorID {
+		version, err = s.Guides.ChangeStatus(ctx, guideID, currentUser.ID, status, expectedVersion)
+	} else {
+		version, err = s.Guides.ChangeStatusAsEditor(ctx, guideID, currentUser.ID, status, expectedVersion)
+	}
+	if err != nil {
 		if err == pgx.ErrNoRows {
-			return ErrNotFound
+			return 0, ErrNotFound
 		}
-		return err
-	}
-	return nil
+		return 0, err
+	}
+
+	action := "guide.unpublish"
+	switch status {
+	case "published":
+		action = "guide.publish"
+	case "archived":
+		action = "guide.archive"
+	}
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     action,
+		TargetType: "guide",
+		TargetID:   guideID,
+		Before:     map[string]any{"status": g.Status, "version": g.Version},
+		After:      map[string]any{"status": status, "version": version},
+	})
+	return version, nil
 }
 
-func (s *GuideService) DeleteGuide(ctx context.Context, currentUser *store.User, guideID string) error {
+func (s *GuideService) DeleteGuide(ctx context.Context, currentUser *store.User, guideID string, expectedVersion int) error {
 	if s.Guides == nil {
 		return errors.New("guide service not configured")
 	}
@@ -177,16 +289,24 @@ func (s *GuideService) DeleteGuide(ctx context.Context, currentUser *store.User,
 		return ErrForbidden
 	}
 
-	if currentUser.ID != g.CreatorID {
-		return errors.New("editor/admin deletes require a store method (DeleteGuideAsEditor)")
+	if currentUser.ID == g.CreatorID {
+		err = s.Guides.DeleteGuide(ctx, guideID, currentUser.ID, expectedVersion)
+	} else {
+		err = s.Guides.DeleteGuideAsEditor(ctx, guideID, currentUser.ID, expectedVersion)
 	}
-
-	if err := s.Guides.DeleteGuide(ctx, guideID, currentUser.ID); err != nil {
+	if err != nil {
 		if err == pgx.ErrNoRows {
 			return ErrNotFound
 		}
 		return err
 	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "guide.delete",
+		TargetType: "guide",
+		TargetID:   guideID,
+		Before:     map[string]any{"title": g.Title, "status": g.Status, "version": g.Version, "creator_id": g.CreatorID},
+	})
 	return nil
 }
 
@@ -206,7 +326,9 @@ func (s *GuideService) GetGuide(ctx context.Context, currentUser *store.User, gu
 		return store.Guide{}, err
 	}
 
-	if g.Status == "published" {
+	// Archived guides stay readable so old links keep working; they are only
+	// left out of listings.
+	if g.Status == "published" || g.Status == "archived" {
 		return g, nil
 	}
 
@@ -220,11 +342,34 @@ func (s *GuideService) GetGuide(ctx context.Context, currentUser *store.User, gu
 	return g, nil
 }
 
-func (s *GuideService) ListPublishedGuides(ctx context.Context, tagFilter, titleSearch string, limit, offset int) ([]store.Guide, error) {
+func (s *GuideService) ListPublishedGuides(ctx context.Context, params store.ListGuidesParams) (store.GuidePage, error) {
 	if s.Guides == nil {
-		return nil, errors.New("guide service not configured")
+		return store.GuidePage{}, errors.New("guide service not configured")
 	}
-	return s.Guides.ListPublishedGuides(ctx, tagFilter, titleSearch, limit, offset)
+	return s.Guides.ListPublishedGuides(ctx, params)
+}
+
+// canSetStatus reports whether u may move a guide it can edit to status.
+// Owners can always take their guide down, but publishing needs its own
+// permission, and so does archiving since archived guides stay public.
+func canSetStatus(u *store.User, status string) bool {
+	switch status {
+	case "published":
+		return can(u, authz.GuidesPublish)
+	case "archived":
+		return can(u, authz.GuidesPublish) || can(u, authz.GuidesEditAny)
+	}
+	return true
+}
+
+// statusTransitionAllowed reports whether a guide may go from one status to
+// another by hand. Only published guides can be archived; anything else
+// would make unreviewed content readable without going through review.
+func statusTransitionAllowed(from, to string) bool {
+	if to == "archived" {
+		return from == "published"
+	}
+	return true
 }
 
 func isAuthedActive(u *store.User) bool {
@@ -238,5 +383,9 @@ func canEditGuide(u *store.User, creatorID string) bool {
 	if u.ID == creatorID {
 		return true
 	}
-	return u.Role == "editor" || u.Role == "admin"
+	return can(u, authz.GuidesEditAny)
+}
+
+func can(u *store.User, p authz.Permission) bool {
+	return u != nil && authz.Can(u.Role, p)
 }
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"skyhow/internal/store"
+)
+
+func TestCanSetStatus(t *testing.T) {
+	tests := []struct {
+		role   string
+		status string
+		want   bool
+	}{
+		{"user", "draft", true},
+		{"user", "published", false},
+		{"user", "archived", false},
+		{"contributor", "archived", false},
+		{"editor", "published", true},
+		{"editor", "archived", true},
+		{"admin", "archived", true},
+	}
+
+	for _, tt := range tests {
+		u := &store.User{ID: "u", Role: tt.role, IsActive: true}
+		if got := canSetStatus(u, tt.status); got != tt.want {
+			t.Errorf("canSetStatus(%s, %s) = %v, want %v", tt.role, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestStatusTransitionAllowed(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"published", "archived", true},
+		{"draft", "archived", false},
+		{"pending_review", "archived", false},
+		{"rejected", "archived", false},
+		{"archived", "archived", false},
+		{"archived", "published", true},
+		{"pending_review", "draft", true},
+	}
+
+	for _, tt := range tests {
+		if got := statusTransitionAllowed(tt.from, tt.to); got != tt.want {
+			t.Errorf("statusTransitionAllowed(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"skyhow/internal/authz"
+	"skyhow/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+type TagService struct {
+	Tags  *store.TagStore
+	Audit *AuditService
+}
+
+func NewTagService(tags *store.TagStore, audit *AuditService) *TagService {
+	return &TagService{Tags: tags, Audit: audit}
+}
+
+func (s *TagService) ListTags(ctx context.Context, limit, offset int) ([]store.TagUsage, error) {
+	if s.Tags == nil {
+		return nil, errors.New("tag service not configured")
+	}
+	return s.Tags.ListTags(ctx, limit, offset)
+}
+
+func (s *TagService) SuggestTags(ctx context.Context, prefix string, limit int) ([]store.TagUsage, error) {
+	if s.Tags == nil {
+		return nil, errors.New("tag service not configured")
+	}
+	if prefix == "" {
+		return nil, ErrInvalidInput
+	}
+	return s.Tags.SuggestTags(ctx, prefix, limit)
+}
+
+func (s *TagService) GetTag(ctx context.Context, name string) (store.TagDetails, error) {
+	if s.Tags == nil {
+		return store.TagDetails{}, errors.New("tag service not configured")
+	}
+	if strings.TrimSpace(name) == "" {
+		return store.TagDetails{}, ErrInvalidInput
+	}
+
+	t, err := s.Tags.GetTag(ctx, name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return store.TagDetails{}, ErrNotFound
+		}
+		return store.TagDetails{}, err
+	}
+	return t, nil
+}
+
+// UpdateTagMetadata replaces a tag's description, category and color. Empty
+// values clear the field.
+func (s *TagService) UpdateTagMetadata(ctx context.Context, currentUser *store.User, name, description, category, color string) error {
+	if s.Tags == nil {
+		return errors.New("tag service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return ErrUnauthenticated
+	}
+	if !can(currentUser, authz.TagsCurate) {
+		return ErrForbidden
+	}
+
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color != "" && !tagColorPattern.MatchString(color) {
+		return ErrInvalidInput
+	}
+
+	err := s.Tags.UpdateMetadata(ctx, name, optionalString(description), optionalString(category), optionalString(color))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "tag.update",
+		TargetType: "tag",
+		TargetID:   name,
+		After: map[string]any{
+			"description": strings.TrimSpace(description),
+			"category":    strings.TrimSpace(category),
+			"color":       color,
+		},
+	})
+	return nil
+}
+
+func (s *TagService) AddAlias(ctx context.Context, currentUser *store.User, name, alias string) error {
+	if err := s.requireTagManager(currentUser); err != nil {
+		return err
+	}
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(alias) == "" {
+		return ErrInvalidInput
+	}
+	if err := s.Tags.AddAlias(ctx, name, alias); err != nil {
+		return tagStoreError(err)
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "tag.alias_add",
+		TargetType: "tag",
+		TargetID:   name,
+		After:      map[string]any{"alias": alias},
+	})
+	return nil
+}
+
+func (s *TagService) RemoveAlias(ctx context.Context, currentUser *store.User, name, alias string) error {
+	if err := s.requireTagManager(currentUser); err != nil {
+		return err
+	}
+	if err := s.Tags.RemoveAlias(ctx, name, alias); err != nil {
+		return tagStoreError(err)
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "tag.alias_remove",
+		TargetType: "tag",
+		TargetID:   name,
+		Before:     map[string]any{"alias": alias},
+	})
+	return nil
+}
+
+func (s *TagService) MergeTags(ctx context.Context, currentUser *store.User, source, target string) (int, error) {
+	if err := s.requireTagManager(currentUser); err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(source) == "" || strings.TrimSpace(target) == "" {
+		return 0, ErrInvalidInput
+	}
+
+	n, err := s.Tags.MergeTags(ctx, source, target, currentUser.ID)
+	if err != nil {
+		return 0, tagStoreError(err)
+	}
+
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     "tag.merge",
+		TargetType: "tag",
+		TargetID:   source,
+		After:      map[string]any{"into": target, "guides": n},
+	})
+	return n, nil
+}
+
+func (s *TagService) requireTagManager(currentUser *store.User) error {
+	if s.Tags == nil {
+		return errors.New("tag service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return ErrUnauthenticated
+	}
+	if !can(currentUser, authz.TagsManage) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func tagStoreError(err error) error {
+	switch err {
+	case pgx.ErrNoRows:
+		return ErrNotFound
+	case store.ErrTagConflict:
+		return ErrConflict
+	default:
+		return err
+	}
+}
+
+func optionalString(v string) *string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// RunOrphanCleanup deletes tags no guide uses anymore every interval until
+// ctx is cancelled. Orphans appear when tags are replaced or guides deleted.
+func (s *TagService) RunOrphanCleanup(ctx context.Context, interval time.Duration) {
+	if s.Tags == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := s.Tags.DeleteOrphans(ctx, 500)
+				if err != nil {
+					log.Println("tag cleanup:", err)
+					break
+				}
+				if n > 0 {
+					log.Println("tag cleanup: deleted", n, "orphaned tags")
+				}
+				if n < 500 {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"skyhow/internal/authz"
+	"skyhow/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// assignableRoles are the roles an admin can give a user.
+var assignableRoles = map[string]bool{
+	"user":        true,
+	"contributor": true,
+	"editor":      true,
+	"admin":       true,
+}
+
+type UserService struct {
+	Users  *store.UserStore
+	Guides *store.GuideStore
+	Audit  *AuditService
+}
+
+func NewUserService(users *store.UserStore, guides *store.GuideStore, audit *AuditService) *UserService {
+	return &UserService{Users: users, Guides: guides, Audit: audit}
+}
+
+func (s *UserService) ListUsers(ctx context.Context, currentUser *store.User, params store.ListUsersParams) ([]store.User, error) {
+	if err := s.requireUserManager(currentUser); err != nil {
+		return nil, err
+	}
+	if params.Role != "" && !assignableRoles[params.Role] {
+		return nil, ErrInvalidInput
+	}
+	params.Search = strings.TrimSpace(params.Search)
+	return s.Users.ListUsers(ctx, params)
+}
+
+// GetUser returns a user together with their guides in every status.
+func (s *UserService) GetUser(ctx context.Context, currentUser *store.User, userID string) (store.User, []store.Guide, error) {
+	if err := s.requireUserManager(currentUser); err != nil {
+		return store.User{}, nil, err
+	}
+	if strings.TrimSpace(userID) == "" {
+		return store.User{}, nil, ErrInvalidInput
+	}
+
+	u, err := s.Users.GetByID(ctx, userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return store.User{}, nil, ErrNotFound
+		}
+		return store.User{}, nil, err
+	}
+
+	guides, err := s.Guides.ListGuidesByCreator(ctx, userID, 200)
+	if err != nil {
+		return store.User{}, nil, err
+	}
+	return u, guides, nil
+}
+
+// ChangeRole sets a user's role. Admins can't change their own role, so the
+// last admin can't lock everyone out by accident.
+func (s *UserService) ChangeRole(ctx context.Context, currentUser *store.User, userID, role string) error {
+	if err := s.requireUserManager(currentUser); err != nil {
+		return err
+	}
+	if strings.TrimSpace(userID) == "" || !assignableRoles[role] {
+		return ErrInvalidInput
+	}
+	if userID == currentUser.ID {
+		return ErrForbidden
+	}
+
+	oldRole, err := s.Users.SetRole(ctx, currentUser.ID, userID, role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if oldRole != role {
+		s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+			Action:     "user.change_role",
+			TargetType: "user",
+			TargetID:   userID,
+			Before:     map[string]any{"role": oldRole},
+			After:      map[string]any{"role": role},
+		})
+	}
+	return nil
+}
+
+// SetUserActive deactivates or reactivates a user. Deactivation logs the user
+// out everywhere.
+func (s *UserService) SetUserActive(ctx context.Context, currentUser *store.User, userID string, active bool) error {
+	if err := s.requireUserManager(currentUser); err != nil {
+		return err
+	}
+	if strings.TrimSpace(userID) == "" {
+		return ErrInvalidInput
+	}
+	if userID == currentUser.ID {
+		return ErrForbidden
+	}
+
+	if err := s.Users.SetActive(ctx, currentUser.ID, userID, active); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	action := "user.reactivate"
+	if !active {
+		action = "user.deactivate"
+	}
+	s.Audit.Record(ctx, currentUser.ID, AuditEntry{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   userID,
+		After:      map[string]any{"is_active": active},
+	})
+	return nil
+}
+
+func (s *UserService) requireUserManager(currentUser *store.User) error {
+	if s.Users == nil || s.Guides == nil {
+		return errors.New("user service not configured")
+	}
+	if !isAuthedActive(currentUser) {
+		return ErrUnauthenticated
+	}
+	if !can(currentUser, authz.UsersManage) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditEvent is one entry in the audit log. Before and After are small JSON
+// summaries of the target around the change, not full copies.
+type AuditEvent struct {
+	ID         string
+	ActorID    *string
+	Action     string
+	TargetType string
+	TargetID   *string
+	Before     map[string]any
+	After      map[string]any
+	IP         *string
+	CreatedAt  time.Time
+}
+
+type ListAuditParams struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditStore struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditStore(db *pgxpool.Pool) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+func (s *AuditStore) Insert(ctx context.Context, e AuditEvent) error {
+	_, err := s.db.Exec(ctx, `
+		insert into public.audit_events (actor_id, action, target_type, target_id, before, after, ip)
+		values ($1, $2, $3, $4, $5, $6, $7);
+	`, e.ActorID, e.Action, e.TargetType, e.TargetID, e.Before, e.After, e.IP)
+	return err
+}
+
+// List returns matching events, newest first. Empty filters match anything.
+func (s *AuditStore) List(ctx context.Context, p ListAuditParams) ([]AuditEvent, error) {
+	if p.Limit <= 0 {
+		p.Limit = 50
+	}
+	if p.Limit > 200 {
+		p.Limit = 200
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+
+	rows, err := s.db.Query(ctx, `
+		select id, actor_id, action, target_type, target_id, before, after, ip, created_at
+		from public.audit_events
+		where ($1::uuid is null or actor_id = $1)
+		  and ($2::text is null or action = $2)
+		  and ($3::text is null or target_type = $3)
+		  and ($4::text is null or target_id = $4)
+		  and ($5::timestamptz is null or created_at >= $5)
+		  and ($6::timestamptz is null or created_at < $6)
+		order by created_at desc, id desc
+		limit $7 offset $8;
+	`, nullIfEmpty(p.ActorID), nullIfEmpty(p.Action), nullIfEmpty(p.TargetType), nullIfEmpty(p.TargetID), p.Since, p.Until, p.Limit, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		if err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetType,
+			&e.TargetID,
+			&e.Before,
+			&e.After,
+			&e.IP,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// GuideCursor is the position of the last guide on a listing page. Rank is
+// only meaningful for full-text searches and is zero otherwise.
+type GuideCursor struct {
+	Rank      float32
+	CreatedAt time.Time
+	ID        string
+}
+
+type guideCursorPayload struct {
+	Rank      float32 `json:"r,omitempty"`
+	CreatedAt string  `json:"t"`
+	ID        string  `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (c GuideCursor) Encode() string {
+	b, _ := json.Marshal(guideCursorPayload{
+		Rank:      c.Rank,
+		CreatedAt: c.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ID:        c.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeGuideCursor(token string) (GuideCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return GuideCursor{}, ErrInvalidCursor
+	}
+
+	var p guideCursorPayload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return GuideCursor{}, ErrInvalidCursor
+	}
+	if p.ID == "" {
+		return GuideCursor{}, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, p.CreatedAt)
+	if err != nil {
+		return GuideCursor{}, ErrInvalidCursor
+	}
+
+	return GuideCursor{Rank: p.Rank, CreatedAt: t, ID: p.ID}, nil
+}
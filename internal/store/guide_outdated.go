@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GuideOutdated is an editor's note that a guide no longer matches the game.
+type GuideOutdated struct {
+	Reason   string
+	MarkedAt time.Time
+	MarkedBy *string
+}
+
+// outdatedColumns scans outdated_reason, outdated_at and outdated_by.
+type outdatedColumns struct {
+	Reason *string
+	At     *time.Time
+	By     *string
+}
+
+func (c outdatedColumns) value() *GuideOutdated {
+	if c.At == nil || c.Reason == nil {
+		return nil
+	}
+	return &GuideOutdated{Reason: *c.Reason, MarkedAt: *c.At, MarkedBy: c.By}
+}
+
+// MarkOutdated flags a guide as outdated, replacing any earlier reason. The
+// flag is editorial metadata rather than content, so it doesn't bump the
+// version and can't conflict with an author's pending edit.
+func (s *GuideStore) MarkOutdated(ctx context.Context, guideID, editorID, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if guideID == "" || editorID == "" {
+		return errors.New("guideID and editorID are required")
+	}
+	if reason == "" {
+		return errors.New("reason is required")
+	}
+
+	ct, err := s.db.Exec(ctx, `
+		update public.guides
+		set outdated_reason = $3,
+		    outdated_at = now(),
+		    outdated_by = $2
+		where id = $1;
+	`, guideID, editorID, reason)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ClearOutdated removes the outdated flag from a guide.
+func (s *GuideStore) ClearOutdated(ctx context.Context, guideID string) error {
+	if guideID == "" {
+		return errors.New("guideID is required")
+	}
+
+	ct, err := s.db.Exec(ctx, `
+		update public.guides
+		set outdated_reason = null,
+		    outdated_at = null,
+		    outdated_by = null
+		where id = $1;
+	`, guideID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
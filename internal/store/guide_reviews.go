@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type GuideReview struct {
+	ID         string
+	GuideID    string
+	ReviewerID *string
+	// Decision is "approved" or "changes_requested".
+	Decision  string
+	Comment   *string
+	CreatedAt time.Time
+}
+
+// ReviewGuide settles a pending review: approving publishes the guide,
+// otherwise it goes to "rejected" until the author resubmits. The guide must
+// still be pending review at expectedVersion.
+func (s *GuideStore) ReviewGuide(ctx context.Context, guideID, reviewerID string, approve bool, comment *string, expectedVersion int) (int, error) {
+	if guideID == "" || reviewerID == "" {
+		return 0, errors.New("guideID and reviewerID are required")
+	}
+
+	status, decision := "rejected", "changes_requested"
+	if approve {
+		status, decision = "published", "approved"
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var version int
+	err = tx.QueryRow(ctx, `
+		update public.guides
+		set status = $2,
+		    publish_at = null,
+		    version = version + 1,
+		    updated_at = now()
+		where id = $1
+		  and status = 'pending_review'
+		  and version = $3
+		returning version;
+	`, guideID, status, expectedVersion).Scan(&version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, versionConflict(ctx, tx, guideID, nil)
+		}
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		insert into public.guide_reviews (guide_id, reviewer_id, decision, comment)
+		values ($1, $2, $3, $4);
+	`, guideID, reviewerID, decision, comment)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *GuideStore) ListReviews(ctx context.Context, guideID string) ([]GuideReview, error) {
+	if guideID == "" {
+		return nil, errors.New("guideID is required")
+	}
+
+	rows, err := s.db.Query(ctx, `
+		select id, guide_id, reviewer_id, decision, comment, created_at
+		from public.guide_reviews
+		where guide_id = $1
+		order by created_at desc, id desc;
+	`, guideID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []GuideReview
+	for rows.Next() {
+		var r GuideReview
+		if err := rows.Scan(&r.ID, &r.GuideID, &r.ReviewerID, &r.Decision, &r.Comment, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPendingReview returns guides waiting for review, longest waiting first.
+func (s *GuideStore) ListPendingReview(ctx context.Context, limit, offset int) ([]Guide, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := s.db.Query(ctx, `
+		select
+		  g.id,
+		  g.creator_id,
+		  g.title,
+		  g.status,
+		  g.version,
+		  g.game_version,
+		  g.outdated_reason,
+		  g.outdated_at,
+		  g.outdated_by,
+		  g.created_at,
+		  g.updated_at,
+		  u.display_name,
+		  u.avatar_url
+		from public.guides g
+		join public.users u on u.id = g.creator_id
+		where g.status = 'pending_review'
+		order by g.updated_at asc, g.id asc
+		limit $1 offset $2;
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectGuideList(ctx, rows)
+}
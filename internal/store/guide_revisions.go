@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type GuideRevision struct {
+	ID       string
+	GuideID  string
+	Revision int
+	Title    string
+	Content  string
+	Tags     []string
+	EditorID *string
+
+	CreatedAt time.Time
+}
+
+func (s *GuideStore) ListRevisions(ctx context.Context, guideID string) ([]GuideRevision, error) {
+	if guideID == "" {
+		return nil, errors.New("guideID is required")
+	}
+
+	rows, err := s.db.Query(ctx, `
+		select id, guide_id, revision, title, tags, editor_id, created_at
+		from public.guide_revisions
+		where guide_id = $1
+		order by revision desc;
+	`, guideID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []GuideRevision
+	for rows.Next() {
+		var r GuideRevision
+		if err := rows.Scan(&r.ID, &r.GuideID, &r.Revision, &r.Title, &r.Tags, &r.EditorID, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *GuideStore) GetRevision(ctx context.Context, guideID string, revision int) (GuideRevision, error) {
+	var r GuideRevision
+	if guideID == "" {
+		return r, errors.New("guideID is required")
+	}
+
+	err := s.db.QueryRow(ctx, `
+		select id, guide_id, revision, title, content, tags, editor_id, created_at
+		from public.guide_revisions
+		where guide_id = $1
+		  and revision = $2;
+	`, guideID, revision).Scan(
+		&r.ID,
+		&r.GuideID,
+		&r.Revision,
+		&r.Title,
+		&r.Content,
+		&r.Tags,
+		&r.EditorID,
+		&r.CreatedAt,
+	)
+	return r, err
+}
+
+// recordRevision snapshots the current title, content and tags of a guide.
+// Callers must hold the guide row lock (any update on the row does) so that
+// revision numbers stay gapless under concurrent writers.
+func recordRevision(ctx context.Context, tx pgx.Tx, guideID, editorID string) error {
+	_, err := tx.Exec(ctx, `
+		insert into public.guide_revisions (guide_id, revision, title, content, tags, editor_id)
+		select
+		  g.id,
+		  coalesce((select max(r.revision) from public.guide_revisions r where r.guide_id = g.id), 0) + 1,
+		  g.title,
+		  g.content,
+		  array(
+		    select t.name
+		    from public.guide_tags gt
+		    join public.tags t on t.id = gt.tag_id
+		    where gt.guide_id = g.id
+		    order by t.name asc
+		  ),
+		  $2
+		from public.guides g
+		where g.id = $1;
+	`, guideID, editorID)
+	return err
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GuideSchedule holds when a guide goes live and when it is archived. Nil
+// means not scheduled.
+type GuideSchedule struct {
+	PublishAt *time.Time
+	ArchiveAt *time.Time
+}
+
+// guideSchedulerLockKey is the advisory lock that keeps replicas from running
+// the same scheduler pass at once.
+const guideSchedulerLockKey int64 = 0x736b79686f7701
+
+// PublishDue publishes up to limit draft or pending guides whose publish_at
+// has passed and returns their ids. publish_at is cleared in the same update,
+// so a guide is published by the scheduler at most once and running this
+// twice is harmless. When another replica holds the scheduler lock it does
+// nothing.
+func (s *GuideStore) PublishDue(ctx context.Context, limit int) ([]string, error) {
+	return s.runScheduled(ctx, `
+		update public.guides g
+		set status = 'published',
+		    publish_at = null,
+		    version = version + 1,
+		    updated_at = now()
+		where g.id in (
+		  select id
+		  from public.guides
+		  where publish_at <= now()
+		    and status in ('draft', 'pending_review')
+		  order by publish_at
+		  limit $1
+		  for update skip locked
+		)
+		returning g.id;
+	`, limit)
+}
+
+// ArchiveDue archives up to limit published guides whose archive_at has
+// passed and returns their ids. Like PublishDue it clears the time it acted
+// on, so republishing an archived guide doesn't archive it again.
+func (s *GuideStore) ArchiveDue(ctx context.Context, limit int) ([]string, error) {
+	return s.runScheduled(ctx, `
+		update public.guides g
+		set status = 'archived',
+		    archive_at = null,
+		    version = version + 1,
+		    updated_at = now()
+		where g.id in (
+		  select id
+		  from public.guides
+		  where archive_at <= now()
+		    and status = 'published'
+		  order by archive_at
+		  limit $1
+		  for update skip locked
+		)
+		returning g.id;
+	`, limit)
+}
+
+func (s *GuideStore) runScheduled(ctx context.Context, sql string, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `select pg_try_advisory_xact_lock($1);`, guideSchedulerLockKey).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, sql, limit)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -3,8 +3,12 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
+	"unicode"
+
+	"skyhow/internal/markdown"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,14 +19,38 @@ type Tag struct {
 	Name string
 }
 
+type GuideAuthor struct {
+	ID          string
+	DisplayName string
+	AvatarURL   *string
+}
+
 type Guide struct {
 	ID        string
 	CreatorID string
+	Author    GuideAuthor
 	Title     string
 	Content   string
 	Status    string
+	Version   int
 	Tags      []Tag
 
+	// ContentHTML is Content rendered to sanitized HTML. It is only loaded
+	// by GetGuideByID.
+	ContentHTML string
+
+	PublishAt *time.Time
+	ArchiveAt *time.Time
+
+	// GameVersion is the game version or patch the guide was written for.
+	GameVersion *string
+	// Outdated is set when an editor has flagged the guide as stale.
+	Outdated *GuideOutdated
+
+	// Headline is a highlighted content snippet as HTML, only set by
+	// full-text search. Everything but the <mark> tags is escaped.
+	Headline string
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -35,7 +63,8 @@ func NewGuideStore(db *pgxpool.Pool) *GuideStore {
 	return &GuideStore{db: db}
 }
 
-func (s *GuideStore) CreateGuide(ctx context.Context, creatorID, title, content string, tags []string) (string, error) {
+// CreateGuide inserts a draft guide. An empty gameVersion leaves it unset.
+func (s *GuideStore) CreateGuide(ctx context.Context, creatorID, title, content, gameVersion string, tags []string, schedule GuideSchedule) (string, error) {
 	if creatorID == "" {
 		return "", errors.New("creatorID is required")
 	}
@@ -49,6 +78,11 @@ func (s *GuideStore) CreateGuide(ctx context.Context, creatorID, title, content
 
 	tagNames := normalizeTagNames(tags)
 
+	contentHTML, err := markdown.Render(content)
+	if err != nil {
+		return "", err
+	}
+
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return "", err
@@ -57,15 +91,19 @@ func (s *GuideStore) CreateGuide(ctx context.Context, creatorID, title, content
 
 	var guideID string
 	err = tx.QueryRow(ctx, `
-		insert into public.guides (creator_id, title, content, status)
-		values ($1, $2, $3, 'draft')
+		insert into public.guides (creator_id, title, content, content_html, status, publish_at, archive_at, game_version)
+		values ($1, $2, $3, $4, 'draft', $5, $6, nullif($7, ''))
 		returning id;
-	`, creatorID, title, content).Scan(&guideID)
+	`, creatorID, title, content, contentHTML, schedule.PublishAt, schedule.ArchiveAt, strings.TrimSpace(gameVersion)).Scan(&guideID)
 	if err != nil {
 		return "", err
 	}
 
 	if len(tagNames) > 0 {
+		tagNames, err = resolveTagAliases(ctx, tx, tagNames)
+		if err != nil {
+			return "", err
+		}
 		if err := upsertTags(ctx, tx, tagNames); err != nil {
 			return "", err
 		}
@@ -74,128 +112,238 @@ func (s *GuideStore) CreateGuide(ctx context.Context, creatorID, title, content
 		}
 	}
 
+	if err := recordRevision(ctx, tx, guideID, creatorID); err != nil {
+		return "", err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return "", err
 	}
 	return guideID, nil
 }
 
-func (s *GuideStore) UpdateGuide(ctx context.Context, guideID, creatorID, title, content string) error {
+// GuideUpdate carries the optional parts of an update. Nil fields are left
+// unchanged; an empty GameVersion clears it.
+type GuideUpdate struct {
+	Tags        *[]string
+	Schedule    *GuideSchedule
+	GameVersion *string
+	Status      *string
+}
+
+func (s *GuideStore) UpdateGuide(ctx context.Context, guideID, creatorID, title, content string, opt GuideUpdate, expectedVersion int) (int, error) {
+	if guideID == "" || creatorID == "" {
+		return 0, errors.New("guideID and creatorID are required")
+	}
+	return s.updateGuide(ctx, guideID, &creatorID, creatorID, title, content, opt, expectedVersion)
+}
+
+func (s *GuideStore) UpdateGuideAsEditor(ctx context.Context, guideID, editorID, title, content string, opt GuideUpdate, expectedVersion int) (int, error) {
+	if guideID == "" || editorID == "" {
+		return 0, errors.New("guideID and editorID are required")
+	}
+	return s.updateGuide(ctx, guideID, nil, editorID, title, content, opt, expectedVersion)
+}
+
+func (s *GuideStore) ChangeStatus(ctx context.Context, guideID, creatorID, status string, expectedVersion int) (int, error) {
+	if guideID == "" || creatorID == "" {
+		return 0, errors.New("guideID and creatorID are required")
+	}
+	return s.changeStatus(ctx, guideID, &creatorID, creatorID, status, expectedVersion)
+}
+
+func (s *GuideStore) ChangeStatusAsEditor(ctx context.Context, guideID, editorID, status string, expectedVersion int) (int, error) {
+	if guideID == "" || editorID == "" {
+		return 0, errors.New("guideID and editorID are required")
+	}
+	return s.changeStatus(ctx, guideID, nil, editorID, status, expectedVersion)
+}
+
+func (s *GuideStore) DeleteGuide(ctx context.Context, guideID, creatorID string, expectedVersion int) error {
 	if guideID == "" || creatorID == "" {
 		return errors.New("guideID and creatorID are required")
 	}
+	return s.deleteGuide(ctx, guideID, &creatorID, creatorID, expectedVersion)
+}
+
+func (s *GuideStore) DeleteGuideAsEditor(ctx context.Context, guideID, editorID string, expectedVersion int) error {
+	if guideID == "" || editorID == "" {
+		return errors.New("guideID and editorID are required")
+	}
+	return s.deleteGuide(ctx, guideID, nil, editorID, expectedVersion)
+}
+
+// updateGuide rewrites title, content and whatever opt sets in one
+// transaction, bumping the version exactly once. A nil creatorID drops the
+// ownership predicate and records the change as a moderation action by
+// actorID.
+func (s *GuideStore) updateGuide(ctx context.Context, guideID string, creatorID *string, actorID, title, content string, opt GuideUpdate, expectedVersion int) (int, error) {
 	title = strings.TrimSpace(title)
 	if title == "" {
-		return errors.New("title is required")
+		return 0, errors.New("title is required")
 	}
 	if content == "" {
-		return errors.New("content is required")
+		return 0, errors.New("content is required")
 	}
 
-	ct, err := s.db.Exec(ctx, `
-		update public.guides
-		set title = $3,
-		    content = $4,
-		    updated_at = now()
-		where id = $1
-		  and creator_id = $2;
-	`, guideID, creatorID, title, content)
+	contentHTML, err := markdown.Render(content)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	if ct.RowsAffected() == 0 {
-		return pgx.ErrNoRows
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
 	}
-	return nil
-}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-func (s *GuideStore) ChangeStatus(ctx context.Context, guideID, creatorID, status string) error {
-	if guideID == "" || creatorID == "" {
-		return errors.New("guideID and creatorID are required")
+	tags, schedule := opt.Tags, opt.Schedule
+	var publishAt, archiveAt *time.Time
+	if schedule != nil {
+		publishAt, archiveAt = schedule.PublishAt, schedule.ArchiveAt
 	}
-	status = strings.ToLower(strings.TrimSpace(status))
-	if status != "draft" && status != "published" {
-		return errors.New("invalid status: must be 'draft' or 'published'")
+	var gameVersion *string
+	if opt.GameVersion != nil {
+		v := strings.TrimSpace(*opt.GameVersion)
+		gameVersion = &v
 	}
 
-	ct, err := s.db.Exec(ctx, `
+	var version int
+	err = tx.QueryRow(ctx, `
 		update public.guides
-		set status = $3,
+		set title = $3,
+		    content = $4,
+		    publish_at = case when $6 then $7 else publish_at end,
+		    archive_at = case when $6 then $8 else archive_at end,
+		    game_version = case when $9::text is null then game_version else nullif($9, '') end,
+		    content_html = $10,
+		    status = coalesce($11, status),
+		    version = version + 1,
 		    updated_at = now()
 		where id = $1
-		  and creator_id = $2;
-	`, guideID, creatorID, status)
+		  and ($2::uuid is null or creator_id = $2)
+		  and version = $5
+		returning version;
+	`, guideID, creatorID, title, content, expectedVersion, schedule != nil, publishAt, archiveAt, gameVersion, contentHTML, opt.Status).Scan(&version)
 	if err != nil {
-		return err
+		if err == pgx.ErrNoRows {
+			return 0, versionConflict(ctx, tx, guideID, creatorID)
+		}
+		return 0, err
 	}
-	if ct.RowsAffected() == 0 {
-		return pgx.ErrNoRows
+
+	var tagNames []string
+	if tags != nil {
+		tagNames, err = resolveTagAliases(ctx, tx, normalizeTagNames(*tags))
+		if err != nil {
+			return 0, err
+		}
+		if len(tagNames) > 0 {
+			if err := upsertTags(ctx, tx, tagNames); err != nil {
+				return 0, err
+			}
+		}
+		if err := replaceGuideTags(ctx, tx, guideID, tagNames); err != nil {
+			return 0, err
+		}
 	}
-	return nil
-}
 
-func (s *GuideStore) DeleteGuide(ctx context.Context, guideID, creatorID string) error {
-	if guideID == "" || creatorID == "" {
-		return errors.New("guideID and creatorID are required")
+	if err := recordRevision(ctx, tx, guideID, actorID); err != nil {
+		return 0, err
 	}
 
-	ct, err := s.db.Exec(ctx, `
-		delete from public.guides
-		where id = $1
-		  and creator_id = $2;
-	`, guideID, creatorID)
-	if err != nil {
-		return err
+	if creatorID == nil {
+		if err := recordModerationAction(ctx, tx, guideID, actorID, "update", nil); err != nil {
+			return 0, err
+		}
+		if tags != nil {
+			detail := strings.Join(tagNames, ",")
+			if err := recordModerationAction(ctx, tx, guideID, actorID, "replace_tags", &detail); err != nil {
+				return 0, err
+			}
+		}
 	}
-	if ct.RowsAffected() == 0 {
-		return pgx.ErrNoRows
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
 	}
-	return nil
+	return version, nil
 }
 
-func (s *GuideStore) ReplaceTags(ctx context.Context, guideID, creatorID string, tags []string) error {
-	if guideID == "" || creatorID == "" {
-		return errors.New("guideID and creatorID are required")
+// changeStatus moves a guide to status. A guide leaving draft or review by
+// any path loses its publish_at, so the scheduler can't later republish it
+// behind its owner's back.
+func (s *GuideStore) changeStatus(ctx context.Context, guideID string, creatorID *string, actorID, status string, expectedVersion int) (int, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status != "draft" && status != "pending_review" && status != "published" && status != "archived" {
+		return 0, errors.New("invalid status: must be 'draft', 'pending_review', 'published' or 'archived'")
 	}
 
-	tagNames := normalizeTagNames(tags)
-
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	var one int
+	var version int
 	err = tx.QueryRow(ctx, `
-		select 1
-		from public.guides
-		where id = $1 and creator_id = $2
-		for update;
-	`, guideID, creatorID).Scan(&one)
+		update public.guides
+		set status = $3,
+		    publish_at = case when $3 in ('draft', 'pending_review') then publish_at end,
+		    version = version + 1,
+		    updated_at = now()
+		where id = $1
+		  and ($2::uuid is null or creator_id = $2)
+		  and version = $4
+		returning version;
+	`, guideID, creatorID, status, expectedVersion).Scan(&version)
 	if err != nil {
-		return err
+		if err == pgx.ErrNoRows {
+			return 0, versionConflict(ctx, tx, guideID, creatorID)
+		}
+		return 0, err
 	}
 
-	if len(tagNames) > 0 {
-		if err := upsertTags(ctx, tx, tagNames); err != nil {
-			return err
+	if creatorID == nil {
+		if err := recordModerationAction(ctx, tx, guideID, actorID, "change_status", &status); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := replaceGuideTags(ctx, tx, guideID, tagNames); err != nil {
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *GuideStore) deleteGuide(ctx context.Context, guideID string, creatorID *string, actorID string, expectedVersion int) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
 		return err
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	_, err = tx.Exec(ctx, `
-		update public.guides
-		set updated_at = now()
-		where id = $1 and creator_id = $2;
-	`, guideID, creatorID)
+	var title string
+	err = tx.QueryRow(ctx, `
+		delete from public.guides
+		where id = $1
+		  and ($2::uuid is null or creator_id = $2)
+		  and version = $3
+		returning title;
+	`, guideID, creatorID, expectedVersion).Scan(&title)
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return versionConflict(ctx, tx, guideID, creatorID)
+		}
 		return err
 	}
 
+	if creatorID == nil {
+		if err := recordModerationAction(ctx, tx, guideID, actorID, "delete", &title); err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -205,108 +353,457 @@ func (s *GuideStore) GetGuideByID(ctx context.Context, guideID string) (Guide, e
 		return g, errors.New("guideID is required")
 	}
 
+	var outdated outdatedColumns
 	err := s.db.QueryRow(ctx, `
-		select id, creator_id, title, content, status, created_at, updated_at
-		from public.guides
-		where id = $1;
+		select
+		  g.id,
+		  g.creator_id,
+		  g.title,
+		  g.content,
+		  g.content_html,
+		  g.status,
+		  g.version,
+		  g.publish_at,
+		  g.archive_at,
+		  g.game_version,
+		  g.outdated_reason,
+		  g.outdated_at,
+		  g.outdated_by,
+		  g.created_at,
+		  g.updated_at,
+		  u.display_name,
+		  u.avatar_url
+		from public.guides g
+		join public.users u on u.id = g.creator_id
+		where g.id = $1;
 	`, guideID).Scan(
 		&g.ID,
 		&g.CreatorID,
 		&g.Title,
 		&g.Content,
+		&g.ContentHTML,
 		&g.Status,
+		&g.Version,
+		&g.PublishAt,
+		&g.ArchiveAt,
+		&g.GameVersion,
+		&outdated.Reason,
+		&outdated.At,
+		&outdated.By,
 		&g.CreatedAt,
 		&g.UpdatedAt,
+		&g.Author.DisplayName,
+		&g.Author.AvatarURL,
 	)
 	if err != nil {
 		return g, err
 	}
+	g.Author.ID = g.CreatorID
+	g.Outdated = outdated.value()
 
-	rows, err := s.db.Query(ctx, `
-		select t.id, t.name
-		from public.tags t
-		join public.guide_tags gt on gt.tag_id = t.id
-		where gt.guide_id = $1
-		order by t.name asc;
-	`, guideID)
+	// Guides written before content_html existed are rendered on read until
+	// their next edit stores it.
+	if g.ContentHTML == "" && g.Content != "" {
+		g.ContentHTML, err = markdown.Render(g.Content)
+		if err != nil {
+			return g, err
+		}
+	}
+
+	tags, err := s.loadTags(ctx, []string{g.ID})
 	if err != nil {
 		return g, err
 	}
+	g.Tags = tags[g.ID]
+
+	return g, nil
+}
+
+// ListGuidesByCreator returns a user's guides in every status, most recently
+// updated first.
+func (s *GuideStore) ListGuidesByCreator(ctx context.Context, creatorID string, limit int) ([]Guide, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	rows, err := s.db.Query(ctx, `
+		select
+		  g.id,
+		  g.creator_id,
+		  g.title,
+		  g.status,
+		  g.version,
+		  g.game_version,
+		  g.outdated_reason,
+		  g.outdated_at,
+		  g.outdated_by,
+		  g.created_at,
+		  g.updated_at,
+		  u.display_name,
+		  u.avatar_url
+		from public.guides g
+		join public.users u on u.id = g.creator_id
+		where g.creator_id = $1
+		order by g.updated_at desc, g.id desc
+		limit $2;
+	`, creatorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectGuideList(ctx, rows)
+}
+
+// collectGuideList scans list rows (id, creator_id, title, status, version,
+// game_version, the outdated columns, created_at, updated_at, author
+// display_name and avatar_url) and loads their tags in one batch.
+func (s *GuideStore) collectGuideList(ctx context.Context, rows pgx.Rows) ([]Guide, error) {
 	defer rows.Close()
 
+	var out []Guide
+	var ids []string
 	for rows.Next() {
+		var g Guide
+		var outdated outdatedColumns
+		if err := rows.Scan(
+			&g.ID,
+			&g.CreatorID,
+			&g.Title,
+			&g.Status,
+			&g.Version,
+			&g.GameVersion,
+			&outdated.Reason,
+			&outdated.At,
+			&outdated.By,
+			&g.CreatedAt,
+			&g.UpdatedAt,
+			&g.Author.DisplayName,
+			&g.Author.AvatarURL,
+		); err != nil {
+			return nil, err
+		}
+		g.Author.ID = g.CreatorID
+		g.Outdated = outdated.value()
+		out = append(out, g)
+		ids = append(ids, g.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.loadTags(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Tags = tags[out[i].ID]
+	}
+
+	return out, nil
+}
+
+// loadTags fetches the tags of several guides in one query, keyed by guide ID.
+func (s *GuideStore) loadTags(ctx context.Context, guideIDs []string) (map[string][]Tag, error) {
+	out := make(map[string][]Tag, len(guideIDs))
+	if len(guideIDs) == 0 {
+		return out, nil
+	}
+
+	rows, err := s.db.Query(ctx, `
+		select gt.guide_id, t.id, t.name
+		from public.guide_tags gt
+		join public.tags t on t.id = gt.tag_id
+		where gt.guide_id = any($1::uuid[])
+		order by gt.guide_id, t.name asc;
+	`, guideIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var guideID string
 		var t Tag
-		if err := rows.Scan(&t.ID, &t.Name); err != nil {
-			return g, err
+		if err := rows.Scan(&guideID, &t.ID, &t.Name); err != nil {
+			return nil, err
 		}
-		g.Tags = append(g.Tags, t)
+		out[guideID] = append(out[guideID], t)
 	}
 	if err := rows.Err(); err != nil {
-		return g, err
+		return nil, err
 	}
+	return out, nil
+}
 
-	return g, nil
+type ListGuidesParams struct {
+	Search string
+
+	// Tags filters to guides carrying all (MatchAll) or any of the given
+	// tags; ExcludeTags drops guides carrying any of those.
+	Tags        []string
+	MatchAll    bool
+	ExcludeTags []string
+
+	// IncludeOutdated keeps guides flagged as outdated in the results. They
+	// are left out by default.
+	IncludeOutdated bool
+
+	Limit  int
+	Offset int
+
+	// After continues a listing after the given cursor. When set, Offset is
+	// ignored.
+	After *GuideCursor
+}
+
+type TagFacet struct {
+	Name  string
+	Count int
 }
 
-func (s *GuideStore) ListPublishedGuides(ctx context.Context, tagFilter string, titleSearch string, limit, offset int) ([]Guide, error) {
+type GuidePage struct {
+	Guides  []Guide
+	Next    *GuideCursor
+	HasMore bool
+
+	// Facets counts tags across the whole filtered result set. It is only
+	// computed for the first page.
+	Facets []TagFacet
+}
+
+// publishedGuideFilter is shared by the listing and facet queries. It expects
+// a "query" CTE with the tsquery and binds $1 tags, $2 search, $3 match-all,
+// $4 excluded tags and $5 include-outdated.
+const publishedGuideFilter = `
+		  g.status = 'published'
+		  and ($5::boolean or g.outdated_at is null)
+		  and ($2::text is null or g.search_vector @@ query.q)
+		  and (
+		    cardinality($1::text[]) = 0
+		    or (
+		      select count(*)
+		      from public.guide_tags gt
+		      join public.tags t on t.id = gt.tag_id
+		      where gt.guide_id = g.id
+		        and t.name = any($1::text[])
+		    ) >= case when $3::boolean then cardinality($1::text[]) else 1 end
+		  )
+		  and not exists (
+		    select 1
+		    from public.guide_tags gt
+		    join public.tags t on t.id = gt.tag_id
+		    where gt.guide_id = g.id
+		      and t.name = any($4::text[])
+		  )`
+
+// guideListColumns are the guide and author columns listings select, in the
+// order the listing scan expects.
+const guideListColumns = `
+		    g.id,
+		    g.creator_id,
+		    g.title,
+		    g.content,
+		    g.status,
+		    g.version,
+		    g.game_version,
+		    g.outdated_reason,
+		    g.outdated_at,
+		    g.outdated_by,
+		    g.created_at,
+		    g.updated_at,
+		    u.display_name,
+		    u.avatar_url`
+
+const guideSearchQueryCTE = `
+		with query as (
+		  select websearch_to_tsquery('english', coalesce($2::text, '')) as q
+		)`
+
+// ListPublishedGuides lists published guides, optionally filtered by tags and
+// a full-text query. With a query, results are ordered by relevance and each
+// guide carries a highlighted content snippet in Headline.
+func (s *GuideStore) ListPublishedGuides(ctx context.Context, p ListGuidesParams) (GuidePage, error) {
+	limit := p.Limit
 	if limit <= 0 {
 		limit = 20
 	}
 	if limit > 100 {
 		limit = 100
 	}
-	if offset < 0 {
+	offset := p.Offset
+	if offset < 0 || p.After != nil {
 		offset = 0
 	}
 
-	tagFilter = strings.ToLower(strings.TrimSpace(tagFilter))
-	titleSearch = strings.TrimSpace(titleSearch)
-	var tagParam *string
+	tags, err := resolveTagAliases(ctx, s.db, normalizeTagNames(p.Tags))
+	if err != nil {
+		return GuidePage{}, err
+	}
+	exclude, err := resolveTagAliases(ctx, s.db, normalizeTagNames(p.ExcludeTags))
+	if err != nil {
+		return GuidePage{}, err
+	}
+	search := strings.TrimSpace(p.Search)
 	var searchParam *string
-	if tagFilter != "" {
-		tagParam = &tagFilter
+	if search != "" {
+		searchParam = &search
+	}
+
+	// One extra row is fetched to tell whether another page exists.
+	args := []any{tags, searchParam, p.MatchAll, exclude, p.IncludeOutdated, limit + 1, offset}
+	var query string
+	if searchParam == nil {
+		// Without a query every rank would be 0, so page on (created_at, id)
+		// alone, which idx_guides_published_created_at_id can serve.
+		keyset := ""
+		if p.After != nil {
+			keyset = `
+		  and (g.created_at, g.id) < ($8::timestamptz, $9::uuid)`
+			args = append(args, p.After.CreatedAt, p.After.ID)
+		}
+		query = guideSearchQueryCTE + `
+		select ` + guideListColumns + `,
+		  0::real,
+		  ''
+		from public.guides g
+		join public.users u on u.id = g.creator_id
+		cross join query
+		where ` + publishedGuideFilter + keyset + `
+		order by g.created_at desc, g.id desc
+		limit $6 offset $7;
+	`
+	} else {
+		var afterRank *float32
+		var afterCreatedAt *time.Time
+		var afterID *string
+		if p.After != nil {
+			afterRank = &p.After.Rank
+			afterCreatedAt = &p.After.CreatedAt
+			afterID = &p.After.ID
+		}
+		args = append(args, afterCreatedAt, afterID, afterRank, headlineOptions)
+		query = guideSearchQueryCTE + `,
+		ranked as (
+		  select ` + guideListColumns + `,
+		    ts_rank(g.search_vector, query.q) as rank
+		  from public.guides g
+		  join public.users u on u.id = g.creator_id
+		  cross join query
+		  where ` + publishedGuideFilter + `
+		),
+		page as (
+		  select *
+		  from ranked r
+		  where $8::timestamptz is null
+		     or (r.rank, r.created_at, r.id) < ($10::real, $8::timestamptz, $9::uuid)
+		  order by r.rank desc, r.created_at desc, r.id desc
+		  limit $6 offset $7
+		)
+		select
+		  p.id,
+		  p.creator_id,
+		  p.title,
+		  p.content,
+		  p.status,
+		  p.version,
+		  p.game_version,
+		  p.outdated_reason,
+		  p.outdated_at,
+		  p.outdated_by,
+		  p.created_at,
+		  p.updated_at,
+		  p.display_name,
+		  p.avatar_url,
+		  p.rank,
+		  ts_headline('english', p.content, query.q, $11::text)
+		from page p
+		cross join query
+		order by p.rank desc, p.created_at desc, p.id desc;
+	`
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return GuidePage{}, err
+	}
+	defer rows.Close()
+
+	var page GuidePage
+	var ranks []float32
+	for rows.Next() {
+		var g Guide
+		var outdated outdatedColumns
+		var rank float32
+		if err := rows.Scan(&g.ID, &g.CreatorID, &g.Title, &g.Content, &g.Status, &g.Version, &g.GameVersion, &outdated.Reason, &outdated.At, &outdated.By, &g.CreatedAt, &g.UpdatedAt, &g.Author.DisplayName, &g.Author.AvatarURL, &rank, &g.Headline); err != nil {
+			return GuidePage{}, err
+		}
+		g.Author.ID = g.CreatorID
+		g.Outdated = outdated.value()
+		g.Headline = headlineHTML(g.Headline)
+		page.Guides = append(page.Guides, g)
+		ranks = append(ranks, rank)
 	}
-	if titleSearch != "" {
-		searchParam = &titleSearch
+	if err := rows.Err(); err != nil {
+		return GuidePage{}, err
 	}
 
-	rows, err := s.db.Query(ctx, `
-		select
-		  g.id,
-		  g.creator_id,
-		  g.title,
-		  g.content,
-		  g.status,
-		  g.created_at,
-		  g.updated_at
+	if len(page.Guides) > limit {
+		page.Guides = page.Guides[:limit]
+		page.HasMore = true
+	}
+
+	ids := make([]string, 0, len(page.Guides))
+	for _, g := range page.Guides {
+		ids = append(ids, g.ID)
+	}
+	guideTags, err := s.loadTags(ctx, ids)
+	if err != nil {
+		return GuidePage{}, err
+	}
+	for i := range page.Guides {
+		page.Guides[i].Tags = guideTags[page.Guides[i].ID]
+	}
+
+	if n := len(page.Guides); n > 0 {
+		last := page.Guides[n-1]
+		page.Next = &GuideCursor{Rank: ranks[n-1], CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	if p.After == nil {
+		page.Facets, err = s.tagFacets(ctx, tags, searchParam, p.MatchAll, exclude, p.IncludeOutdated)
+		if err != nil {
+			return GuidePage{}, err
+		}
+	}
+	return page, nil
+}
+
+func (s *GuideStore) tagFacets(ctx context.Context, tags []string, search *string, matchAll bool, exclude []string, includeOutdated bool) ([]TagFacet, error) {
+	rows, err := s.db.Query(ctx, guideSearchQueryCTE+`
+		select t.name, count(*)
 		from public.guides g
-		where g.status = 'published'
-		  and ($2::text is null or g.title ilike ('%' || $2 || '%'))
-		  and (
-		    $1::text is null
-		    or exists (
-		      select 1
-		      from public.guide_tags gt
-		      join public.tags t on t.id = gt.tag_id
-		      where gt.guide_id = g.id
-		        and t.name = $1
-		    )
-		  )
-		order by g.created_at desc
-		limit $3 offset $4;
-	`, tagParam, searchParam, limit, offset)
+		cross join query
+		join public.guide_tags gt on gt.guide_id = g.id
+		join public.tags t on t.id = gt.tag_id
+		where `+publishedGuideFilter+`
+		group by t.name
+		order by count(*) desc, t.name asc
+		limit 50;
+	`, tags, search, matchAll, exclude, includeOutdated)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var out []Guide
+	var out []TagFacet
 	for rows.Next() {
-		var g Guide
-		if err := rows.Scan(&g.ID, &g.CreatorID, &g.Title, &g.Content, &g.Status, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		var f TagFacet
+		if err := rows.Scan(&f.Name, &f.Count); err != nil {
 			return nil, err
 		}
-		out = append(out, g)
+		out = append(out, f)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -319,13 +816,10 @@ func normalizeTagNames(tags []string) []string {
 	out := make([]string, 0, len(tags))
 
 	for _, t := range tags {
-		n := strings.ToLower(strings.TrimSpace(t))
+		n := normalizeTagName(t)
 		if n == "" {
 			continue
 		}
-		if len(n) > 50 {
-			n = n[:50]
-		}
 		if _, ok := seen[n]; ok {
 			continue
 		}
@@ -335,11 +829,82 @@ func normalizeTagNames(tags []string) []string {
 	return out
 }
 
+// normalizeTagName lowercases a tag, turns whitespace and underscores into
+// single hyphens and drops anything that isn't a-z, 0-9 or a hyphen, so
+// "Floor  7" and "floor_7" both become "floor-7".
+func normalizeTagName(t string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(t) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+		case r == '-' || r == '_' || unicode.IsSpace(r):
+			pendingHyphen = true
+		}
+	}
+
+	n := b.String()
+	if len(n) > 50 {
+		n = strings.TrimRight(n[:50], "-")
+	}
+	return n
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// resolveTagAliases maps alias names to their canonical tag names, keeping
+// the input order and dropping duplicates the mapping produces.
+func resolveTagAliases(ctx context.Context, q querier, tagNames []string) ([]string, error) {
+	if len(tagNames) == 0 {
+		return tagNames, nil
+	}
+
+	rows, err := q.Query(ctx, `
+		select n.name, coalesce(t.name, n.name)
+		from unnest($1::text[]) with ordinality as n(name, ord)
+		left join public.tag_aliases a on a.alias = n.name
+		left join public.tags t on t.id = a.tag_id
+		order by n.ord;
+	`, tagNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]struct{}, len(tagNames))
+	out := make([]string, 0, len(tagNames))
+	for rows.Next() {
+		var name, canonical string
+		if err := rows.Scan(&name, &canonical); err != nil {
+			return nil, err
+		}
+		if _, ok := seen[canonical]; ok {
+			continue
+		}
+		seen[canonical] = struct{}{}
+		out = append(out, canonical)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// upsertTags makes sure every name exists in tags. Existing rows are touched
+// with a no-op update so they stay row-locked until the caller commits, which
+// keeps TagStore.DeleteOrphans from removing them before they're linked.
 func upsertTags(ctx context.Context, tx pgx.Tx, tagNames []string) error {
 	_, err := tx.Exec(ctx, `
 		insert into public.tags (name)
 		select distinct unnest($1::text[])
-		on conflict (name) do nothing;
+		on conflict (name) do update set name = excluded.name;
 	`, tagNames)
 	return err
 }
@@ -350,16 +915,70 @@ func replaceGuideTags(ctx context.Context, tx pgx.Tx, guideID string, tagNames [
 		return err
 	}
 
-	if len(tagNames) == 0 {
+	if len(tagNames) > 0 {
+		_, err = tx.Exec(ctx, `
+			insert into public.guide_tags (guide_id, tag_id)
+			select $1, t.id
+			from public.tags t
+			where t.name = any($2::text[])
+			on conflict do nothing;
+		`, guideID, tagNames)
+		if err != nil {
+			return err
+		}
+	}
+
+	return refreshTagsText(ctx, tx, []string{guideID})
+}
+
+// refreshTagsText recomputes tags_text, which feeds the generated
+// search_vector column since that can't look into guide_tags itself.
+func refreshTagsText(ctx context.Context, tx pgx.Tx, guideIDs []string) error {
+	if len(guideIDs) == 0 {
 		return nil
 	}
 
-	_, err = tx.Exec(ctx, `
-		insert into public.guide_tags (guide_id, tag_id)
-		select $1, t.id
-		from public.tags t
-		where t.name = any($2::text[])
-		on conflict do nothing;
-	`, guideID, tagNames)
+	_, err := tx.Exec(ctx, `
+		update public.guides g
+		set tags_text = coalesce((
+		  select string_agg(t.name, ' ' order by t.name)
+		  from public.guide_tags gt
+		  join public.tags t on t.id = gt.tag_id
+		  where gt.guide_id = g.id
+		), '')
+		where g.id = any($1::uuid[]);
+	`, guideIDs)
 	return err
 }
+
+func recordModerationAction(ctx context.Context, tx pgx.Tx, guideID, moderatorID, action string, detail *string) error {
+	_, err := tx.Exec(ctx, `
+		insert into public.guide_moderation_actions (guide_id, moderator_id, action, detail)
+		values ($1, $2, $3, $4);
+	`, guideID, moderatorID, action, detail)
+	return err
+}
+
+type VersionConflictError struct {
+	Current int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("guide version conflict: current version is %d", e.Current)
+}
+
+// versionConflict explains why a versioned write matched no rows: either the
+// guide is gone (or not owned by creatorID), or someone else changed it first.
+func versionConflict(ctx context.Context, tx pgx.Tx, guideID string, creatorID *string) error {
+	var current int
+	err := tx.QueryRow(ctx, `
+		select version
+		from public.guides
+		where id = $1
+		  and ($2::uuid is null or creator_id = $2);
+	`, guideID, creatorID).Scan(&current)
+	if err != nil {
+		return err
+	}
+	return &VersionConflictError{Current: current}
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"html"
+	"strings"
+)
+
+// ts_headline returns fragments of the raw content, so it can't be trusted to
+// produce safe HTML. It marks matches with these control characters instead,
+// and headlineHTML escapes the text before turning them into <mark> tags.
+const (
+	headlineStart = "\x02"
+	headlineStop  = "\x03"
+)
+
+const headlineOptions = `StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", MaxFragments=2, MaxWords=30, MinWords=10`
+
+// headlineHTML escapes a ts_headline result and wraps the marked matches in
+// <mark>. Stray markers from the content itself can't unbalance the tags.
+func headlineHTML(h string) string {
+	if h == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	open := false
+	for h != "" {
+		i := strings.IndexAny(h, headlineStart+headlineStop)
+		if i < 0 {
+			b.WriteString(html.EscapeString(h))
+			break
+		}
+		b.WriteString(html.EscapeString(h[:i]))
+		switch {
+		case h[i:i+1] == headlineStart && !open:
+			b.WriteString("<mark>")
+			open = true
+		case h[i:i+1] == headlineStop && open:
+			b.WriteString("</mark>")
+			open = false
+		}
+		h = h[i+1:]
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package store
+
+import "testing"
+
+func TestHeadlineHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain", "just text", "just text"},
+		{"match", "kill \x02golems\x03 fast", "kill <mark>golems</mark> fast"},
+		{"escapes content", "<img src=x onerror=alert(1)> \x02golem\x03", "&lt;img src=x onerror=alert(1)&gt; <mark>golem</mark>"},
+		{"escapes inside match", "\x02<b>\x03", "<mark>&lt;b&gt;</mark>"},
+		{"stray stop", "a\x03b", "ab"},
+		{"nested start", "\x02a\x02b\x03", "<mark>ab</mark>"},
+		{"unclosed", "\x02a", "<mark>a</mark>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := headlineHTML(tt.in); got != tt.want {
+				t.Fatalf("headlineHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
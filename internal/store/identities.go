@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrIdentityConflict = errors.New("identity is linked to another user")
+	ErrLastIdentity     = errors.New("cannot unlink the last identity")
+)
+
+// IdentityProfile is what an OAuth provider tells us about a user.
+type IdentityProfile struct {
+	Provider       string
+	ProviderUserID string
+	Email          string
+	EmailVerified  bool
+	DisplayName    string
+	AvatarURL      *string
+	// Role, when set, replaces the user's role on every login.
+	Role string
+}
+
+type Identity struct {
+	ID             string
+	UserID         string
+	Provider       string
+	ProviderUserID string
+	Email          *string
+	CreatedAt      time.Time
+	LastLoginAt    *time.Time
+}
+
+// ResolvedLogin is the user an OAuth login resolved to.
+type ResolvedLogin struct {
+	UserID string
+	// PreviousRole is set when the profile's role changed the user's role.
+	PreviousRole string
+	// IsActive is false for deactivated users, who must not get a session.
+	IsActive bool
+}
+
+// ResolveLogin finds or creates the user behind an OAuth login. Users are
+// matched by provider identity first; a verified email matching an existing
+// user links the identity to that user; otherwise a new user is created.
+func (s *UserStore) ResolveLogin(ctx context.Context, p IdentityProfile) (ResolvedLogin, error) {
+	if p.Provider == "" || p.ProviderUserID == "" {
+		return ResolvedLogin{}, errors.New("provider and provider user id are required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ResolvedLogin{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	email := nullIfEmpty(p.Email)
+	var res ResolvedLogin
+
+	err = tx.QueryRow(ctx, `
+		update public.user_identities
+		set email = $3,
+		    last_login_at = now()
+		where provider = $1
+		  and provider_user_id = $2
+		returning user_id;
+	`, p.Provider, p.ProviderUserID, email).Scan(&res.UserID)
+	switch {
+	case err == nil:
+		err = tx.QueryRow(ctx, `
+			update public.users
+			set display_name = $2,
+			    avatar_url = $3,
+			    updated_at = now()
+			where id = $1
+			returning is_active;
+		`, res.UserID, p.DisplayName, p.AvatarURL).Scan(&res.IsActive)
+		if err != nil {
+			return ResolvedLogin{}, err
+		}
+
+	case err == pgx.ErrNoRows:
+		res.UserID, res.IsActive, err = findOrCreateUserForIdentity(ctx, tx, p)
+		if err != nil {
+			return ResolvedLogin{}, err
+		}
+		_, err = tx.Exec(ctx, `
+			insert into public.user_identities (user_id, provider, provider_user_id, email, last_login_at)
+			values ($1, $2, $3, $4, now());
+		`, res.UserID, p.Provider, p.ProviderUserID, email)
+		if err != nil {
+			return ResolvedLogin{}, err
+		}
+
+	default:
+		return ResolvedLogin{}, err
+	}
+
+	if p.Role != "" {
+		err = tx.QueryRow(ctx, `
+			update public.users u
+			set role = $2,
+			    updated_at = now()
+			from (
+			  select id, role
+			  from public.users
+			  where id = $1
+			  for update
+			) old
+			where u.id = old.id
+			  and old.role <> $2
+			returning old.role;
+		`, res.UserID, p.Role).Scan(&res.PreviousRole)
+		if err != nil && err != pgx.ErrNoRows {
+			return ResolvedLogin{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ResolvedLogin{}, err
+	}
+	return res, nil
+}
+
+func findOrCreateUserForIdentity(ctx context.Context, tx pgx.Tx, p IdentityProfile) (userID string, active bool, err error) {
+	if p.Email != "" && p.EmailVerified {
+		err = tx.QueryRow(ctx, `
+			update public.users
+			set display_name = $2,
+			    avatar_url = $3,
+			    email_verified = true,
+			    updated_at = now()
+			where lower(email) = lower($1)
+			returning id, is_active;
+		`, p.Email, p.DisplayName, p.AvatarURL).Scan(&userID, &active)
+		if err == nil {
+			return userID, active, nil
+		}
+		if err != pgx.ErrNoRows {
+			return "", false, err
+		}
+	}
+
+	// An unverified email that belongs to someone else must not be claimed,
+	// so the new user is created without it.
+	var email *string
+	if p.Email != "" {
+		var taken bool
+		err = tx.QueryRow(ctx, `
+			select exists (select 1 from public.users where lower(email) = lower($1));
+		`, p.Email).Scan(&taken)
+		if err != nil {
+			return "", false, err
+		}
+		if !taken {
+			email = &p.Email
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		insert into public.users (display_name, avatar_url, email, email_verified)
+		values ($1, $2, $3, $4)
+		returning id, is_active;
+	`, p.DisplayName, p.AvatarURL, email, email != nil && p.EmailVerified).Scan(&userID, &active)
+	return userID, active, err
+}
+
+// LinkIdentity attaches another provider identity to an existing user.
+// Linking an identity the user already has is a no-op.
+func (s *UserStore) LinkIdentity(ctx context.Context, userID string, p IdentityProfile) error {
+	if userID == "" || p.Provider == "" || p.ProviderUserID == "" {
+		return errors.New("user id, provider and provider user id are required")
+	}
+
+	var ownerID string
+	err := s.db.QueryRow(ctx, `
+		insert into public.user_identities (user_id, provider, provider_user_id, email)
+		values ($1, $2, $3, $4)
+		on conflict (provider, provider_user_id)
+		do update set email = excluded.email
+		returning user_id;
+	`, userID, p.Provider, p.ProviderUserID, nullIfEmpty(p.Email)).Scan(&ownerID)
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrIdentityConflict
+	}
+	return nil
+}
+
+func (s *UserStore) ListIdentities(ctx context.Context, userID string) ([]Identity, error) {
+	rows, err := s.db.Query(ctx, `
+		select id, user_id, provider, provider_user_id, email, created_at, last_login_at
+		from public.user_identities
+		where user_id = $1
+		order by created_at asc;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Identity
+	for rows.Next() {
+		var i Identity
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.ProviderUserID, &i.Email, &i.CreatedAt, &i.LastLoginAt); err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnlinkIdentity removes one of the user's identities. The user's identity
+// rows are locked first so two concurrent unlinks can't remove the last one.
+func (s *UserStore) UnlinkIdentity(ctx context.Context, userID, identityID string) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		select id
+		from public.user_identities
+		where user_id = $1
+		for update;
+	`, userID)
+	if err != nil {
+		return err
+	}
+	found := false
+	count := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		count++
+		if id == identityID {
+			found = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !found {
+		return pgx.ErrNoRows
+	}
+	if count <= 1 {
+		return ErrLastIdentity
+	}
+
+	_, err = tx.Exec(ctx, `delete from public.user_identities where id = $1;`, identityID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OAuthState is everything an OAuth callback needs to know about the login
+// attempt that started it. Like session tokens, the state value itself is only
+// stored as a hash.
+type OAuthState struct {
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	ReturnTo     string
+	// LinkUserID is set when the flow links an identity to an existing user
+	// instead of logging in.
+	LinkUserID *string
+	ExpiresAt  time.Time
+}
+
+type OAuthStateStore struct {
+	db *pgxpool.Pool
+}
+
+func NewOAuthStateStore(db *pgxpool.Pool) *OAuthStateStore {
+	return &OAuthStateStore{db: db}
+}
+
+func (s *OAuthStateStore) Create(ctx context.Context, state string, st OAuthState) error {
+	_, err := s.db.Exec(ctx, `
+		insert into public.oauth_states (state_hash, provider, code_verifier, nonce, return_to, link_user_id, expires_at)
+		values ($1, $2, $3, $4, $5, $6, $7);
+	`, hashToken(state), st.Provider, st.CodeVerifier, st.Nonce, st.ReturnTo, st.LinkUserID, st.ExpiresAt)
+	return err
+}
+
+// Consume deletes the state and returns it, so each state can be used once.
+// Unknown, already used and expired states return pgx.ErrNoRows.
+func (s *OAuthStateStore) Consume(ctx context.Context, state string) (OAuthState, error) {
+	var st OAuthState
+	err := s.db.QueryRow(ctx, `
+		delete from public.oauth_states
+		where state_hash = $1
+		returning provider, code_verifier, nonce, return_to, link_user_id, expires_at;
+	`, hashToken(state)).Scan(
+		&st.Provider,
+		&st.CodeVerifier,
+		&st.Nonce,
+		&st.ReturnTo,
+		&st.LinkUserID,
+		&st.ExpiresAt,
+	)
+	if err != nil {
+		return OAuthState{}, err
+	}
+	if !st.ExpiresAt.After(time.Now()) {
+		return OAuthState{}, pgx.ErrNoRows
+	}
+	return st, nil
+}
+
+// DeleteExpired removes up to batchSize states whose login attempt was
+// abandoned.
+func (s *OAuthStateStore) DeleteExpired(ctx context.Context, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	ct, err := s.db.Exec(ctx, `
+		delete from public.oauth_states
+		where state_hash in (
+		  select state_hash
+		  from public.oauth_states
+		  where expires_at <= now()
+		  limit $1
+		);
+	`, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
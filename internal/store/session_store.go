@@ -2,12 +2,37 @@ package store
 
 import (
 	"context"
-	"log"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Session is a login session. The bearer token handed to the client is never
+// stored; sessions are looked up by its SHA-256 hash.
+type Session struct {
+	ID string
+	// PublicID identifies a session in API responses.
+	PublicID   string
+	UserID     string
+	UserAgent  *string
+	IP         *string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+
+	AbsoluteExpiresAt time.Time
+}
+
+type SessionMeta struct {
+	UserAgent string
+	IP        string
+}
+
 type SessionStore struct {
 	db *pgxpool.Pool
 }
@@ -16,33 +41,209 @@ func NewSessionStore(db *pgxpool.Pool) *SessionStore {
 	return &SessionStore{db: db}
 }
 
-func (s *SessionStore) Create(ctx context.Context, userID string, expiresAt time.Time) (string, error) {
-	var sessionID string
-	err := s.db.QueryRow(ctx, `
-		insert into public.sessions (user_id, expires_at)
-		values ($1, $2)
-		returning id;
-	`, userID, expiresAt).Scan(&sessionID)
-	return sessionID, err
-}
+// Create starts a session that expires at expiresAt and can be renewed up to
+// absoluteExpiresAt but never past it. It returns the bearer token for the
+// session cookie.
+func (s *SessionStore) Create(ctx context.Context, userID string, expiresAt, absoluteExpiresAt time.Time, meta SessionMeta) (string, error) {
+	token, err := newSessionToken()
+	if err != nil {
+		return "", err
+	}
 
-func (s *SessionStore) Delete(ctx context.Context, sessionID string) error {
-	_, err := s.db.Exec(ctx, `delete from public.sessions where id = $1;`, sessionID)
+	_, err = s.db.Exec(ctx, `
+		insert into public.sessions (user_id, token_hash, expires_at, absolute_expires_at, user_agent, ip)
+		values ($1, $2, $3, $4, $5, $6);
+	`, userID, hashToken(token), expiresAt, absoluteExpiresAt, nullIfEmpty(truncate(meta.UserAgent, 512)), nullIfEmpty(meta.IP))
 	if err != nil {
-		log.Println(err)
-		return err
+		return "", err
 	}
-	log.Println("deleted", sessionID)
-	return nil
+	return token, nil
 }
 
-func (s *SessionStore) GetUserIDBySessionID(ctx context.Context, sessionID string) (string, error) {
+// Delete ends the session behind token and returns whose it was. Unknown
+// tokens return an empty user id and no error.
+func (s *SessionStore) Delete(ctx context.Context, token string) (string, error) {
 	var userID string
 	err := s.db.QueryRow(ctx, `
-		select user_id
+		delete from public.sessions
+		where token_hash = $1
+		returning user_id;
+	`, hashToken(token)).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return userID, err
+}
+
+// Get looks up a live session by the bearer token from the cookie.
+func (s *SessionStore) Get(ctx context.Context, token string) (Session, error) {
+	var sess Session
+	err := s.db.QueryRow(ctx, `
+		select id, public_id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, absolute_expires_at
 		from public.sessions
-		where id = $1
+		where token_hash = $1
 		  and expires_at > now();
-	`, sessionID).Scan(&userID)
-	return userID, err
+	`, hashToken(token)).Scan(
+		&sess.ID,
+		&sess.PublicID,
+		&sess.UserID,
+		&sess.UserAgent,
+		&sess.IP,
+		&sess.CreatedAt,
+		&sess.LastSeenAt,
+		&sess.ExpiresAt,
+		&sess.AbsoluteExpiresAt,
+	)
+	return sess, err
+}
+
+// Touch records activity on a session. Writes closer than minInterval to the
+// previous one are skipped so busy clients don't cause a write per request.
+func (s *SessionStore) Touch(ctx context.Context, sessionID, ip string, minInterval time.Duration) error {
+	_, err := s.db.Exec(ctx, `
+		update public.sessions
+		set last_seen_at = now(),
+		    ip = coalesce($2, ip)
+		where id = $1
+		  and last_seen_at < now() - $3::interval;
+	`, sessionID, nullIfEmpty(ip), minInterval)
+	return err
+}
+
+// Renew slides a session's expiry to now+ttl, capped at its absolute expiry,
+// once less than half of ttl is left. It reports whether the expiry moved;
+// concurrent requests race on the same predicate so only one of them renews.
+func (s *SessionStore) Renew(ctx context.Context, sessionID string, ttl time.Duration) (time.Time, bool, error) {
+	var expiresAt time.Time
+	err := s.db.QueryRow(ctx, `
+		update public.sessions
+		set expires_at = least(now() + $2::interval, absolute_expires_at)
+		where id = $1
+		  and expires_at > now()
+		  and expires_at < now() + $2::interval / 2
+		  and least(now() + $2::interval, absolute_expires_at) > expires_at
+		returning expires_at;
+	`, sessionID, ttl).Scan(&expiresAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	return expiresAt, true, nil
+}
+
+// DeleteExpired removes up to batchSize expired sessions and returns how many
+// were deleted.
+func (s *SessionStore) DeleteExpired(ctx context.Context, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	ct, err := s.db.Exec(ctx, `
+		delete from public.sessions
+		where id in (
+		  select id
+		  from public.sessions
+		  where expires_at <= now()
+		  limit $1
+		);
+	`, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+func (s *SessionStore) ListByUser(ctx context.Context, userID string) ([]Session, error) {
+	rows, err := s.db.Query(ctx, `
+		select id, public_id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, absolute_expires_at
+		from public.sessions
+		where user_id = $1
+		  and expires_at > now()
+		order by last_seen_at desc;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Session
+	for rows.Next() {
+		var sess Session
+		if err := rows.Scan(
+			&sess.ID,
+			&sess.PublicID,
+			&sess.UserID,
+			&sess.UserAgent,
+			&sess.IP,
+			&sess.CreatedAt,
+			&sess.LastSeenAt,
+			&sess.ExpiresAt,
+			&sess.AbsoluteExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, sess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *SessionStore) DeleteByPublicID(ctx context.Context, userID, publicID string) error {
+	ct, err := s.db.Exec(ctx, `
+		delete from public.sessions
+		where public_id = $1
+		  and user_id = $2;
+	`, publicID, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteOthers revokes every session of userID except keepSessionID and
+// returns how many were removed.
+func (s *SessionStore) DeleteOthers(ctx context.Context, userID, keepSessionID string) (int64, error) {
+	ct, err := s.db.Exec(ctx, `
+		delete from public.sessions
+		where user_id = $1
+		  and id <> $2;
+	`, userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+func nullIfEmpty(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func truncate(v string, n int) string {
+	if len(v) > n {
+		return strings.ToValidUTF8(v[:n], "")
+	}
+	return v
 }
@@ -0,0 +1,408 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrTagConflict = errors.New("tag conflict")
+
+type TagUsage struct {
+	Tag
+	Description    *string
+	Category       *string
+	Color          *string
+	PublishedCount int
+}
+
+type TagDetails struct {
+	TagUsage
+	Aliases []string
+}
+
+type TagStore struct {
+	db *pgxpool.Pool
+}
+
+func NewTagStore(db *pgxpool.Pool) *TagStore {
+	return &TagStore{db: db}
+}
+
+func (s *TagStore) ListTags(ctx context.Context, limit, offset int) ([]TagUsage, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := s.db.Query(ctx, `
+		select t.id, t.name, t.description, t.category, t.color, count(g.id) as published_count
+		from public.tags t
+		join public.guide_tags gt on gt.tag_id = t.id
+		join public.guides g on g.id = gt.guide_id and g.status = 'published'
+		group by t.id
+		order by published_count desc, t.name asc
+		limit $1 offset $2;
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanTagUsages(rows)
+}
+
+// SuggestTags returns tags starting with prefix for editor autocomplete,
+// including tags only used by drafts.
+func (s *TagStore) SuggestTags(ctx context.Context, prefix string, limit int) ([]TagUsage, error) {
+	if limit <= 0 || limit > 20 {
+		limit = 10
+	}
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+
+	rows, err := s.db.Query(ctx, `
+		select
+		  t.id,
+		  t.name,
+		  t.description,
+		  t.category,
+		  t.color,
+		  (
+		    select count(*)
+		    from public.guide_tags gt
+		    join public.guides g on g.id = gt.guide_id
+		    where gt.tag_id = t.id
+		      and g.status = 'published'
+		  ) as published_count
+		from public.tags t
+		where t.name like $1 || '%'
+		order by published_count desc, t.name asc
+		limit $2;
+	`, escapeLike(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanTagUsages(rows)
+}
+
+// DeleteOrphans removes up to batchSize tags that no guide references and
+// nobody curated (no metadata, no aliases). Rows locked by a concurrent
+// upsertTags are skipped, so a tag that is being attached right now is never
+// deleted from under that transaction.
+func (s *TagStore) DeleteOrphans(ctx context.Context, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	ct, err := s.db.Exec(ctx, `
+		with orphans as (
+		  select t.id
+		  from public.tags t
+		  where t.description is null
+		    and t.category is null
+		    and t.color is null
+		    and not exists (
+		      select 1
+		      from public.guide_tags gt
+		      where gt.tag_id = t.id
+		    )
+		    and not exists (
+		      select 1
+		      from public.tag_aliases a
+		      where a.tag_id = t.id
+		    )
+		  limit $1
+		  for update skip locked
+		)
+		delete from public.tags
+		where id in (select id from orphans);
+	`, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+func (s *TagStore) GetTag(ctx context.Context, name string) (TagDetails, error) {
+	var t TagDetails
+	name = normalizeTagName(name)
+
+	err := s.db.QueryRow(ctx, `
+		select
+		  t.id,
+		  t.name,
+		  t.description,
+		  t.category,
+		  t.color,
+		  (
+		    select count(*)
+		    from public.guide_tags gt
+		    join public.guides g on g.id = gt.guide_id
+		    where gt.tag_id = t.id
+		      and g.status = 'published'
+		  ),
+		  array(
+		    select a.alias
+		    from public.tag_aliases a
+		    where a.tag_id = t.id
+		    order by a.alias asc
+		  )
+		from public.tags t
+		where t.name = $1;
+	`, name).Scan(
+		&t.ID,
+		&t.Name,
+		&t.Description,
+		&t.Category,
+		&t.Color,
+		&t.PublishedCount,
+		&t.Aliases,
+	)
+	return t, err
+}
+
+func (s *TagStore) UpdateMetadata(ctx context.Context, name string, description, category, color *string) error {
+	ct, err := s.db.Exec(ctx, `
+		update public.tags
+		set description = $2,
+		    category = $3,
+		    color = $4
+		where name = $1;
+	`, normalizeTagName(name), description, category, color)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// AddAlias makes alias resolve to the tag called name. An alias can't shadow
+// an existing tag; merge the two tags instead.
+func (s *TagStore) AddAlias(ctx context.Context, name, alias string) error {
+	name = normalizeTagName(name)
+	alias = normalizeTagName(alias)
+	if name == "" || alias == "" {
+		return errors.New("tag name and alias are required")
+	}
+	if name == alias {
+		return ErrTagConflict
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var tagID string
+	err = tx.QueryRow(ctx, `select id from public.tags where name = $1;`, name).Scan(&tagID)
+	if err != nil {
+		return err
+	}
+
+	var shadowed bool
+	err = tx.QueryRow(ctx, `select exists (select 1 from public.tags where name = $1);`, alias).Scan(&shadowed)
+	if err != nil {
+		return err
+	}
+	if shadowed {
+		return ErrTagConflict
+	}
+
+	ct, err := tx.Exec(ctx, `
+		insert into public.tag_aliases (alias, tag_id)
+		values ($1, $2)
+		on conflict (alias) do nothing;
+	`, alias, tagID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrTagConflict
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *TagStore) RemoveAlias(ctx context.Context, name, alias string) error {
+	ct, err := s.db.Exec(ctx, `
+		delete from public.tag_aliases a
+		using public.tags t
+		where a.tag_id = t.id
+		  and t.name = $1
+		  and a.alias = $2;
+	`, normalizeTagName(name), normalizeTagName(alias))
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// MergeTags folds the tag source into target in one transaction: guides
+// tagged source get target instead, source's aliases move over, missing
+// metadata is copied, and source itself becomes an alias of target. Every
+// retagged guide gets a new version and a revision credited to editorID. It
+// returns the number of guides that were retagged.
+func (s *TagStore) MergeTags(ctx context.Context, source, target, editorID string) (int, error) {
+	source = normalizeTagName(source)
+	target = normalizeTagName(target)
+	if source == "" || target == "" {
+		return 0, errors.New("source and target tags are required")
+	}
+	if source == target {
+		return 0, ErrTagConflict
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var sourceID, targetID string
+	rows, err := tx.Query(ctx, `
+		select id, name
+		from public.tags
+		where name in ($1, $2)
+		order by id
+		for update;
+	`, source, target)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if name == source {
+			sourceID = id
+		} else {
+			targetID = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if sourceID == "" || targetID == "" {
+		return 0, pgx.ErrNoRows
+	}
+
+	var guideIDs []string
+	err = tx.QueryRow(ctx, `
+		select coalesce(array_agg(guide_id), '{}')
+		from public.guide_tags
+		where tag_id = $1;
+	`, sourceID).Scan(&guideIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		insert into public.guide_tags (guide_id, tag_id)
+		select gt.guide_id, $2
+		from public.guide_tags gt
+		where gt.tag_id = $1
+		on conflict do nothing;
+	`, sourceID, targetID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		update public.tags t
+		set description = coalesce(t.description, src.description),
+		    category = coalesce(t.category, src.category),
+		    color = coalesce(t.color, src.color)
+		from public.tags src
+		where t.id = $2
+		  and src.id = $1;
+	`, sourceID, targetID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `update public.tag_aliases set tag_id = $2 where tag_id = $1;`, sourceID, targetID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `delete from public.tags where id = $1;`, sourceID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		insert into public.tag_aliases (alias, tag_id)
+		values ($1, $2)
+		on conflict (alias) do update set tag_id = excluded.tag_id;
+	`, source, targetID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := refreshTagsText(ctx, tx, guideIDs); err != nil {
+		return 0, err
+	}
+
+	// A retag is an edit like any other: writers holding the old version
+	// must see a conflict, and the history must show it.
+	if len(guideIDs) > 0 {
+		_, err = tx.Exec(ctx, `
+			update public.guides
+			set version = version + 1,
+			    updated_at = now()
+			where id = any($1::uuid[]);
+		`, guideIDs)
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, guideID := range guideIDs {
+		if err := recordRevision(ctx, tx, guideID, editorID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(guideIDs), nil
+}
+
+func scanTagUsages(rows pgx.Rows) ([]TagUsage, error) {
+	defer rows.Close()
+
+	var out []TagUsage
+	for rows.Next() {
+		var t TagUsage
+		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.Category, &t.Color, &t.PublishedCount); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `%`, `\%`)
+	s = strings.ReplaceAll(s, `_`, `\_`)
+	return s
+}
@@ -2,8 +2,9 @@ package store
 
 import (
 	"context"
-	"errors"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,6 +16,7 @@ type User struct {
 	EmailVerified bool
 	Role          string
 	IsActive      bool
+	CreatedAt     time.Time
 }
 
 type UserStore struct {
@@ -25,34 +27,10 @@ func NewUserStore(db *pgxpool.Pool) *UserStore {
 	return &UserStore{db: db}
 }
 
-func (s *UserStore) UpsertByEmail(ctx context.Context, email string, displayName string, avatarURL *string, emailVerified bool) (string, error) {
-	if email == "" {
-		return "", errors.New("email is required to upsert user")
-	}
-
-	var userID string
-	err := s.db.QueryRow(ctx, `
-		insert into public.users (display_name, avatar_url, email, email_verified)
-		values ($1, $2, $3, $4)
-		on conflict (lower(email)) where email is not null
-		do update set
-			display_name = excluded.display_name,
-			avatar_url = excluded.avatar_url,
-			email_verified = excluded.email_verified,
-			updated_at = now()
-		returning id;
-	`, displayName, avatarURL, email, emailVerified).Scan(&userID)
-
-	if err != nil {
-		return "", err
-	}
-	return userID, nil
-}
-
 func (s *UserStore) GetByID(ctx context.Context, userID string) (User, error) {
 	var u User
 	err := s.db.QueryRow(ctx, `
-		select id, display_name, avatar_url, email, email_verified, role, is_active
+		select id, display_name, avatar_url, email, email_verified, role, is_active, created_at
 		from public.users
 		where id = $1;
 	`, userID).Scan(
@@ -63,6 +41,167 @@ func (s *UserStore) GetByID(ctx context.Context, userID string) (User, error) {
 		&u.EmailVerified,
 		&u.Role,
 		&u.IsActive,
+		&u.CreatedAt,
 	)
 	return u, err
 }
+
+type ListUsersParams struct {
+	// Search matches display names and emails, case-insensitively.
+	Search string
+	Role   string
+	Active *bool
+	Limit  int
+	Offset int
+}
+
+func (s *UserStore) ListUsers(ctx context.Context, p ListUsersParams) ([]User, error) {
+	if p.Limit <= 0 {
+		p.Limit = 50
+	}
+	if p.Limit > 200 {
+		p.Limit = 200
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+
+	var search, role *string
+	if p.Search != "" {
+		pattern := "%" + escapeLike(p.Search) + "%"
+		search = &pattern
+	}
+	if p.Role != "" {
+		role = &p.Role
+	}
+
+	rows, err := s.db.Query(ctx, `
+		select id, display_name, avatar_url, email, email_verified, role, is_active, created_at
+		from public.users
+		where ($1::text is null or display_name ilike $1 or email ilike $1)
+		  and ($2::text is null or role = $2)
+		  and ($3::boolean is null or is_active = $3)
+		order by created_at desc, id desc
+		limit $4 offset $5;
+	`, search, role, p.Active, p.Limit, p.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(
+			&u.ID,
+			&u.DisplayName,
+			&u.AvatarURL,
+			&u.Email,
+			&u.EmailVerified,
+			&u.Role,
+			&u.IsActive,
+			&u.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetRole changes a user's role, records who did it and returns the previous
+// role. It returns pgx.ErrNoRows when the user doesn't exist.
+func (s *UserStore) SetRole(ctx context.Context, adminID, userID, role string) (string, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var oldRole string
+	err = tx.QueryRow(ctx, `
+		select role from public.users where id = $1 for update;
+	`, userID).Scan(&oldRole)
+	if err != nil {
+		return "", err
+	}
+	if oldRole == role {
+		return oldRole, tx.Commit(ctx)
+	}
+
+	_, err = tx.Exec(ctx, `
+		update public.users
+		set role = $2,
+		    updated_at = now()
+		where id = $1;
+	`, userID, role)
+	if err != nil {
+		return "", err
+	}
+
+	detail := oldRole + " -> " + role
+	if err := recordUserAdminAction(ctx, tx, userID, adminID, "change_role", &detail); err != nil {
+		return "", err
+	}
+
+	return oldRole, tx.Commit(ctx)
+}
+
+// SetActive deactivates or reactivates a user. Deactivating also deletes all
+// of the user's sessions in the same transaction.
+func (s *UserStore) SetActive(ctx context.Context, adminID, userID string, active bool) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var wasActive bool
+	err = tx.QueryRow(ctx, `
+		select is_active from public.users where id = $1 for update;
+	`, userID).Scan(&wasActive)
+	if err != nil {
+		return err
+	}
+
+	if !active {
+		_, err = tx.Exec(ctx, `delete from public.sessions where user_id = $1;`, userID)
+		if err != nil {
+			return err
+		}
+	}
+	if wasActive == active {
+		return tx.Commit(ctx)
+	}
+
+	_, err = tx.Exec(ctx, `
+		update public.users
+		set is_active = $2,
+		    updated_at = now()
+		where id = $1;
+	`, userID, active)
+	if err != nil {
+		return err
+	}
+
+	action := "reactivate"
+	if !active {
+		action = "deactivate"
+	}
+	if err := recordUserAdminAction(ctx, tx, userID, adminID, action, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func recordUserAdminAction(ctx context.Context, tx pgx.Tx, userID, adminID, action string, detail *string) error {
+	_, err := tx.Exec(ctx, `
+		insert into public.user_admin_actions (user_id, admin_id, action, detail)
+		values ($1, $2, $3, $4);
+	`, userID, adminID, action, detail)
+	return err
+}
@@ -0,0 +1,3 @@
+drop index if exists idx_guide_moderation_actions_moderator_id;
+drop index if exists idx_guide_moderation_actions_guide_id;
+drop table if exists public.guide_moderation_actions;
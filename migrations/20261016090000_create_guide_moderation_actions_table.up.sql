@@ -0,0 +1,18 @@
+create table if not exists public.guide_moderation_actions (
+  id uuid primary key default gen_random_uuid(),
+
+  guide_id uuid not null,
+
+  moderator_id uuid not null
+    references public.users(id)
+    on delete cascade,
+
+  action text not null
+    check (action in ('update', 'replace_tags', 'change_status', 'delete')),
+  detail text null,
+
+  created_at timestamptz not null default now()
+);
+
+create index if not exists idx_guide_moderation_actions_guide_id on public.guide_moderation_actions(guide_id);
+create index if not exists idx_guide_moderation_actions_moderator_id on public.guide_moderation_actions(moderator_id);
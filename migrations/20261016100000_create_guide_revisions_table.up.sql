@@ -0,0 +1,42 @@
+create table if not exists public.guide_revisions (
+  id uuid primary key default gen_random_uuid(),
+
+  guide_id uuid not null
+    references public.guides(id)
+    on delete cascade,
+
+  revision integer not null,
+
+  title text not null,
+  content text not null,
+  tags text[] not null default '{}',
+
+  editor_id uuid null
+    references public.users(id)
+    on delete set null,
+
+  created_at timestamptz not null default now(),
+
+  constraint guide_revisions_guide_revision_key unique (guide_id, revision)
+);
+
+insert into public.guide_revisions (guide_id, revision, title, content, tags, editor_id, created_at)
+select
+  g.id,
+  1,
+  g.title,
+  g.content,
+  coalesce(
+    array(
+      select t.name
+      from public.guide_tags gt
+      join public.tags t on t.id = gt.tag_id
+      where gt.guide_id = g.id
+      order by t.name asc
+    ),
+    '{}'
+  ),
+  g.creator_id,
+  g.updated_at
+from public.guides g
+on conflict do nothing;
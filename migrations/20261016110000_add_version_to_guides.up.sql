@@ -0,0 +1,2 @@
+alter table public.guides
+  add column if not exists version integer not null default 1;
@@ -0,0 +1,7 @@
+drop index if exists idx_guides_search_vector;
+
+alter table public.guides
+  drop column if exists search_vector;
+
+alter table public.guides
+  drop column if exists tags_text;
@@ -0,0 +1,20 @@
+alter table public.guides
+  add column if not exists tags_text text not null default '';
+
+update public.guides g
+set tags_text = coalesce((
+  select string_agg(t.name, ' ' order by t.name)
+  from public.guide_tags gt
+  join public.tags t on t.id = gt.tag_id
+  where gt.guide_id = g.id
+), '');
+
+alter table public.guides
+  add column if not exists search_vector tsvector
+    generated always as (
+      setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+      setweight(to_tsvector('english', coalesce(tags_text, '')), 'B') ||
+      setweight(to_tsvector('english', coalesce(content, '')), 'C')
+    ) stored;
+
+create index if not exists idx_guides_search_vector on public.guides using gin (search_vector);
@@ -0,0 +1 @@
+drop index if exists idx_guides_published_created_at_id;
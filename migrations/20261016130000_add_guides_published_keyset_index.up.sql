@@ -0,0 +1,3 @@
+create index if not exists idx_guides_published_created_at_id
+  on public.guides (created_at desc, id desc)
+  where status = 'published';
@@ -0,0 +1 @@
+drop index if exists idx_tags_name_prefix;
@@ -0,0 +1 @@
+create index if not exists idx_tags_name_prefix on public.tags (name text_pattern_ops);
@@ -0,0 +1,10 @@
+drop index if exists idx_tag_aliases_tag_id;
+drop table if exists public.tag_aliases;
+
+alter table public.tags
+  drop constraint if exists tags_color_check;
+
+alter table public.tags
+  drop column if exists color,
+  drop column if exists category,
+  drop column if exists description;
@@ -0,0 +1,19 @@
+alter table public.tags
+  add column if not exists description text null,
+  add column if not exists category text null,
+  add column if not exists color text null;
+
+alter table public.tags
+  add constraint tags_color_check check (color is null or color ~ '^#[0-9a-f]{6}$');
+
+create table if not exists public.tag_aliases (
+  alias text primary key,
+
+  tag_id uuid not null
+    references public.tags(id)
+    on delete cascade,
+
+  created_at timestamptz not null default now()
+);
+
+create index if not exists idx_tag_aliases_tag_id on public.tag_aliases(tag_id);
@@ -0,0 +1,7 @@
+drop index if exists ux_sessions_public_id;
+
+alter table public.sessions
+  drop column if exists ip,
+  drop column if exists user_agent,
+  drop column if exists last_seen_at,
+  drop column if exists public_id;
@@ -0,0 +1,7 @@
+alter table public.sessions
+  add column if not exists public_id uuid not null default gen_random_uuid(),
+  add column if not exists last_seen_at timestamptz not null default now(),
+  add column if not exists user_agent text null,
+  add column if not exists ip text null;
+
+create unique index if not exists ux_sessions_public_id on public.sessions(public_id);
@@ -0,0 +1,2 @@
+alter table public.sessions
+  drop column if exists absolute_expires_at;
@@ -0,0 +1,9 @@
+alter table public.sessions
+  add column if not exists absolute_expires_at timestamptz null;
+
+update public.sessions
+set absolute_expires_at = greatest(expires_at, created_at + interval '90 days')
+where absolute_expires_at is null;
+
+alter table public.sessions
+  alter column absolute_expires_at set not null;
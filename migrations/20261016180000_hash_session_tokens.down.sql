@@ -0,0 +1,7 @@
+-- Hashed tokens can't be turned back into ids, so every session is dropped.
+delete from public.sessions;
+
+drop index if exists ux_sessions_token_hash;
+
+alter table public.sessions
+  drop column if exists token_hash;
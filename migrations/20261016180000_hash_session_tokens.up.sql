@@ -0,0 +1,16 @@
+-- Sessions are looked up by sha256(cookie value) from now on. Existing
+-- sessions used their uuid id as the cookie value, so hashing id::text keeps
+-- them valid. The id is rotated in the same update so the table no longer
+-- holds a usable bearer token.
+alter table public.sessions
+  add column if not exists token_hash bytea null;
+
+update public.sessions
+set token_hash = digest(id::text, 'sha256'),
+    id = gen_random_uuid()
+where token_hash is null;
+
+alter table public.sessions
+  alter column token_hash set not null;
+
+create unique index if not exists ux_sessions_token_hash on public.sessions(token_hash);
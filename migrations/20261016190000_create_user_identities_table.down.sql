@@ -0,0 +1,2 @@
+drop index if exists idx_user_identities_user_id;
+drop table if exists public.user_identities;
@@ -0,0 +1,18 @@
+create table if not exists public.user_identities (
+  id uuid primary key default gen_random_uuid(),
+
+  user_id uuid not null
+    references public.users(id)
+    on delete cascade,
+
+  provider text not null,
+  provider_user_id text not null,
+  email text null,
+
+  created_at timestamptz not null default now(),
+  last_login_at timestamptz null,
+
+  constraint user_identities_provider_user_key unique (provider, provider_user_id)
+);
+
+create index if not exists idx_user_identities_user_id on public.user_identities(user_id);
@@ -0,0 +1,2 @@
+drop index if exists idx_oauth_states_expires_at;
+drop table if exists public.oauth_states;
@@ -0,0 +1,17 @@
+create table if not exists public.oauth_states (
+  state_hash bytea primary key,
+
+  provider text not null,
+  code_verifier text not null,
+  nonce text not null,
+  return_to text not null default '/',
+
+  link_user_id uuid null
+    references public.users(id)
+    on delete cascade,
+
+  created_at timestamptz not null default now(),
+  expires_at timestamptz not null
+);
+
+create index if not exists idx_oauth_states_expires_at on public.oauth_states(expires_at);
@@ -0,0 +1,3 @@
+drop index if exists idx_user_admin_actions_admin_id;
+drop index if exists idx_user_admin_actions_user_id;
+drop table if exists public.user_admin_actions;
@@ -0,0 +1,20 @@
+create table if not exists public.user_admin_actions (
+  id uuid primary key default gen_random_uuid(),
+
+  user_id uuid not null
+    references public.users(id)
+    on delete cascade,
+
+  admin_id uuid not null
+    references public.users(id)
+    on delete cascade,
+
+  action text not null
+    check (action in ('change_role', 'deactivate', 'reactivate')),
+  detail text null,
+
+  created_at timestamptz not null default now()
+);
+
+create index if not exists idx_user_admin_actions_user_id on public.user_admin_actions(user_id);
+create index if not exists idx_user_admin_actions_admin_id on public.user_admin_actions(admin_id);
@@ -0,0 +1,5 @@
+drop index if exists idx_audit_events_action;
+drop index if exists idx_audit_events_target;
+drop index if exists idx_audit_events_actor_id;
+drop index if exists idx_audit_events_created_at;
+drop table if exists public.audit_events;
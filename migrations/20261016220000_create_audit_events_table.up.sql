@@ -0,0 +1,23 @@
+create table if not exists public.audit_events (
+  id uuid primary key default gen_random_uuid(),
+
+  -- null for events without a logged-in actor; kept when the user is deleted
+  actor_id uuid null
+    references public.users(id)
+    on delete set null,
+
+  action text not null,
+  target_type text not null,
+  target_id text null,
+
+  before jsonb null,
+  after jsonb null,
+
+  ip text null,
+  created_at timestamptz not null default now()
+);
+
+create index if not exists idx_audit_events_created_at on public.audit_events(created_at desc, id desc);
+create index if not exists idx_audit_events_actor_id on public.audit_events(actor_id, created_at desc);
+create index if not exists idx_audit_events_target on public.audit_events(target_type, target_id, created_at desc);
+create index if not exists idx_audit_events_action on public.audit_events(action, created_at desc);
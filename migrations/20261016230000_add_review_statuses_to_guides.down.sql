@@ -0,0 +1,10 @@
+update public.guides
+set status = 'draft'
+where status in ('pending_review', 'rejected');
+
+alter table public.guides
+  drop constraint if exists guides_status_check;
+
+alter table public.guides
+  add constraint guides_status_check
+    check (status in ('draft', 'published'));
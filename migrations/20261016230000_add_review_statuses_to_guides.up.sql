@@ -0,0 +1,6 @@
+alter table public.guides
+  drop constraint if exists guides_status_check;
+
+alter table public.guides
+  add constraint guides_status_check
+    check (status in ('draft', 'pending_review', 'published', 'rejected'));
@@ -0,0 +1,3 @@
+drop index if exists idx_guides_pending_review;
+drop index if exists idx_guide_reviews_guide_id;
+drop table if exists public.guide_reviews;
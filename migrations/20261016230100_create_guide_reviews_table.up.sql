@@ -0,0 +1,23 @@
+create table if not exists public.guide_reviews (
+  id uuid primary key default gen_random_uuid(),
+
+  guide_id uuid not null
+    references public.guides(id)
+    on delete cascade,
+
+  reviewer_id uuid null
+    references public.users(id)
+    on delete set null,
+
+  decision text not null
+    check (decision in ('approved', 'changes_requested')),
+  comment text null,
+
+  created_at timestamptz not null default now()
+);
+
+create index if not exists idx_guide_reviews_guide_id on public.guide_reviews(guide_id, created_at desc);
+
+-- the review queue lists pending guides oldest first
+create index if not exists idx_guides_pending_review on public.guides(updated_at)
+  where status = 'pending_review';
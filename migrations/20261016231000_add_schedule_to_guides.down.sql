@@ -0,0 +1,17 @@
+drop index if exists idx_guides_archive_at;
+drop index if exists idx_guides_publish_at;
+
+update public.guides
+set status = 'draft'
+where status = 'archived';
+
+alter table public.guides
+  drop constraint if exists guides_status_check;
+
+alter table public.guides
+  add constraint guides_status_check
+    check (status in ('draft', 'pending_review', 'published', 'rejected'));
+
+alter table public.guides
+  drop column if exists archive_at,
+  drop column if exists publish_at;
@@ -0,0 +1,16 @@
+alter table public.guides
+  add column if not exists publish_at timestamptz null,
+  add column if not exists archive_at timestamptz null;
+
+alter table public.guides
+  drop constraint if exists guides_status_check;
+
+alter table public.guides
+  add constraint guides_status_check
+    check (status in ('draft', 'pending_review', 'published', 'rejected', 'archived'));
+
+create index if not exists idx_guides_publish_at on public.guides(publish_at)
+  where publish_at is not null;
+
+create index if not exists idx_guides_archive_at on public.guides(archive_at)
+  where archive_at is not null;
@@ -0,0 +1,10 @@
+drop index if exists idx_guides_outdated_at;
+
+alter table public.guides
+  drop constraint if exists guides_outdated_reason_check;
+
+alter table public.guides
+  drop column if exists outdated_by,
+  drop column if exists outdated_at,
+  drop column if exists outdated_reason,
+  drop column if exists game_version;
@@ -0,0 +1,15 @@
+alter table public.guides
+  add column if not exists game_version text null
+    check (game_version is null or char_length(game_version) between 1 and 32),
+  add column if not exists outdated_reason text null,
+  add column if not exists outdated_at timestamptz null,
+  add column if not exists outdated_by uuid null
+    references public.users(id)
+    on delete set null;
+
+alter table public.guides
+  add constraint guides_outdated_reason_check
+    check ((outdated_at is null) = (outdated_reason is null));
+
+create index if not exists idx_guides_outdated_at on public.guides(outdated_at)
+  where outdated_at is not null;
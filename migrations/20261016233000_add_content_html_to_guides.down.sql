@@ -0,0 +1,2 @@
+alter table public.guides
+  drop column if exists content_html;
@@ -0,0 +1,4 @@
+-- Rendered, sanitized HTML of content. Existing guides start empty and are
+-- rendered on read until their next edit.
+alter table public.guides
+  add column if not exists content_html text not null default '';
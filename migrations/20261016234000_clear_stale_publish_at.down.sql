@@ -0,0 +1,2 @@
+-- Cleared publish_at values can't be restored, and nothing depends on them.
+select 1;
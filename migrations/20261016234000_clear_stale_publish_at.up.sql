@@ -0,0 +1,7 @@
+-- Guides published or reviewed by hand kept their publish_at, which let the
+-- scheduler republish them after a later unpublish. Status changes clear it
+-- now; this clears what was left behind.
+update public.guides
+set publish_at = null
+where publish_at is not null
+  and status not in ('draft', 'pending_review');
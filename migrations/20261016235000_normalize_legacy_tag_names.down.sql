@@ -0,0 +1,3 @@
+-- Renamed and merged tags can't be split back apart.
+alter table public.tags
+  drop constraint if exists tags_name_check;
@@ -0,0 +1,101 @@
+-- Tags created before the stricter normalization (lowercase a-z, 0-9 and
+-- single hyphens, at most 50 characters) can't be looked up, aliased or
+-- merged anymore, because every lookup normalizes its input first. Rename
+-- them to their normalized form; when that name is already taken by a tag or
+-- an alias, merge into that tag instead. Tags that normalize to nothing are
+-- dropped.
+
+create temporary table tag_renames as
+select
+  t.id,
+  rtrim(left(trim(both '-' from regexp_replace(
+    regexp_replace(lower(t.name), '[^a-z0-9[:space:]_-]', '', 'g'),
+    '[[:space:]_-]+', '-', 'g'
+  )), 50), '-') as new_name,
+  null::uuid as target_id
+from public.tags t;
+
+delete from tag_renames r
+using public.tags t
+where t.id = r.id
+  and t.name = r.new_name;
+
+-- An existing normalized tag wins, then an alias of one, then the first
+-- legacy tag of each group, which keeps its id and is renamed.
+update tag_renames r
+set target_id = coalesce(
+  (select t.id from public.tags t where t.name = r.new_name),
+  (
+    select a.tag_id
+    from public.tag_aliases a
+    where a.alias = r.new_name
+      and a.tag_id not in (select id from tag_renames)
+  ),
+  (select r2.id from tag_renames r2 where r2.new_name = r.new_name order by r2.id limit 1)
+)
+where r.new_name <> '';
+
+create temporary table tag_rename_guides as
+select distinct gt.guide_id
+from public.guide_tags gt
+join tag_renames r on r.id = gt.tag_id;
+
+-- Merge tags into their target.
+insert into public.guide_tags (guide_id, tag_id)
+select gt.guide_id, r.target_id
+from public.guide_tags gt
+join tag_renames r on r.id = gt.tag_id
+where r.target_id <> r.id
+on conflict do nothing;
+
+update public.tags t
+set description = coalesce(t.description, src.description),
+    category = coalesce(t.category, src.category),
+    color = coalesce(t.color, src.color)
+from (
+  select distinct on (r.target_id) r.target_id, s.description, s.category, s.color
+  from tag_renames r
+  join public.tags s on s.id = r.id
+  where r.target_id <> r.id
+  order by r.target_id, r.id
+) src
+where t.id = src.target_id;
+
+update public.tag_aliases a
+set tag_id = r.target_id
+from tag_renames r
+where a.tag_id = r.id
+  and r.target_id <> r.id;
+
+delete from public.tags t
+using tag_renames r
+where t.id = r.id
+  and (r.target_id is null or r.target_id <> r.id);
+
+-- Rename the tags that stay.
+update public.tags t
+set name = r.new_name
+from tag_renames r
+where t.id = r.id
+  and r.target_id = r.id;
+
+-- A canonical name can't also be an alias.
+delete from public.tag_aliases a
+using public.tags t
+where t.name = a.alias;
+
+update public.guides g
+set tags_text = coalesce((
+  select string_agg(t.name, ' ' order by t.name)
+  from public.guide_tags gt
+  join public.tags t on t.id = gt.tag_id
+  where gt.guide_id = g.id
+), '')
+where g.id in (select guide_id from tag_rename_guides);
+
+drop table tag_rename_guides;
+drop table tag_renames;
+
+alter table public.tags
+  add constraint tags_name_check
+    check (char_length(name) <= 50 and name ~ '^[a-z0-9]+(-[a-z0-9]+)*$');